@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jezek/chess-game-generator/storage"
+)
+
+// runDiff runs the diff subcommand, which reports games added, deleted and modified between two storage files.
+// It exits with status 1 if the files differ.
+func runDiff(args []string) {
+	if len(args) != 2 {
+		log.Fatalf("Subcommand diff expects two storage files, got %d arguments", len(args))
+	}
+	d, err := storage.Diff(args[0], args[1])
+	if err != nil {
+		log.Fatalf("Error comparing storage files: %v", err)
+	}
+	for _, c := range []struct {
+		name  string
+		seeds []int64
+	}{{"Added", d.Added}, {"Deleted", d.Deleted}, {"Modified", d.Modified}} {
+		fmt.Printf("%s %d games: %v\n", c.name, len(c.seeds), c.seeds)
+	}
+	if len(d.Added)+len(d.Deleted)+len(d.Modified) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/andrewbackes/chess/game"
+	"github.com/jezek/chess-game-generator/gen"
+)
+
+// gameFilter discards games, which should not be considered for selection nor storage.
+type gameFilter struct {
+	// Description of discarded games for the final report.
+	description string
+	accept      func(*game.Game) bool
+	discarded   int
+}
+
+// gameFilters accepts games accepted by all filters.
+type gameFilters []*gameFilter
+
+// accept reports whether all filters accept the game and counts discarded games for the first filter, which didn't accept it.
+func (fs gameFilters) accept(g *game.Game) bool {
+	for _, f := range fs {
+		if !f.accept(g) {
+			f.discarded += 1
+			return false
+		}
+	}
+	return true
+}
+
+// report logs the number of discarded games by each filter.
+func (fs gameFilters) report() {
+	for _, f := range fs {
+		log.Printf("Discarded %d games %s", f.discarded, f.description)
+	}
+}
+
+// withPositions returns the game, or the game rehydrated from stored moves, if the game was loaded from storage and has no positions.
+func withPositions(g *game.Game) (*game.Game, error) {
+	if len(g.Positions) > 0 {
+		return g, nil
+	}
+	rg, err := gen.RehydrateFrom(g.Tags[gen.TagFEN], strings.Fields(g.Tags["sanMoves"]))
+	if err != nil {
+		return nil, err
+	}
+	rg.Tags = g.Tags
+	return rg, nil
+}
+
+// terminalFilter returns filter accepting only games with the terminal status, or nil for "any" terminal status.
+func terminalFilter(terminal string) (*gameFilter, error) {
+	var accept func(game.GameStatus) bool
+	switch terminal {
+	case "any":
+		return nil, nil
+	case "checkmate":
+		accept = func(gs game.GameStatus) bool { return gs&(game.WhiteCheckmated|game.BlackCheckmated) != 0 }
+	case "stalemate":
+		accept = func(gs game.GameStatus) bool { return gs&game.Stalemate != 0 }
+	case "draw":
+		accept = func(gs game.GameStatus) bool { return gs&game.Draw != 0 }
+	default:
+		return nil, fmt.Errorf("unknown terminal status %q", terminal)
+	}
+	return &gameFilter{
+		description: "not ending with " + terminal,
+		accept: func(g *game.Game) bool {
+			if len(g.Positions) == 0 && terminal == "checkmate" {
+				// Stored games don't have to be replayed, checkmate is marked in the last SAN move.
+				return strings.HasSuffix(g.Tags["sanMoves"], "#")
+			}
+			rg, err := withPositions(g)
+			if err != nil {
+				log.Printf("Error replaying stored game #%s: %v", g.Tags["#"], err)
+				return false
+			}
+			return accept(gen.GameStatus(rg))
+		},
+	}, nil
+}
+
+// requireFilter returns filter accepting only games containing required features, or nil if nothing is required.
+// Required features are comma separated, supported features are "promotion" and "castling", which requires both sides to castle.
+func requireFilter(require string) (*gameFilter, error) {
+	if require == "" {
+		return nil, nil
+	}
+	accepts := []func(*game.Game) bool{}
+	missing := []string{}
+	for _, feature := range strings.Split(require, ",") {
+		switch feature {
+		case "promotion":
+			accepts = append(accepts, hasPromotion)
+			missing = append(missing, "promotion")
+		case "castling":
+			accepts = append(accepts, bothCastled)
+			missing = append(missing, "castling of both sides")
+		default:
+			return nil, fmt.Errorf("unknown required feature %q", feature)
+		}
+	}
+	return &gameFilter{
+		description: "without " + strings.Join(missing, " or "),
+		accept: func(g *game.Game) bool {
+			for _, accept := range accepts {
+				if !accept(g) {
+					return false
+				}
+			}
+			return true
+		},
+	}, nil
+}
+
+// Reports whether a pawn was promoted in the game.
+func hasPromotion(g *game.Game) bool {
+	if n, ok := g.Tags[gen.TagPromotions]; ok {
+		return n != "0"
+	}
+	// Promotions are marked with "=" in SAN moves of stored games.
+	if len(g.Positions) == 0 {
+		return strings.Contains(g.Tags["sanMoves"], "=")
+	}
+	return gen.Summarize(g).Promotions > 0
+}
+
+// Reports whether both sides castled in the game.
+func bothCastled(g *game.Game) bool {
+	// Castling is marked with "O-O" in SAN moves of stored games. White plays even moves, unless Black moves first in the starting position.
+	if len(g.Positions) == 0 {
+		first := 0
+		if fields := strings.Fields(g.Tags[gen.TagFEN]); len(fields) > 1 && fields[1] == "b" {
+			first = 1
+		}
+		white, black := false, false
+		for i, san := range strings.Fields(g.Tags["sanMoves"]) {
+			if strings.HasPrefix(san, "O-O") {
+				if (i+first)%2 == 0 {
+					white = true
+				} else {
+					black = true
+				}
+			}
+		}
+		return white && black
+	}
+	stats := gen.Summarize(g)
+	return stats.WhiteCastled && stats.BlackCastled
+}
+
+// minLengthFilter returns filter accepting only games with at least min half-moves, or nil if min is not positive.
+func minLengthFilter(min int) *gameFilter {
+	if min <= 0 {
+		return nil
+	}
+	return &gameFilter{
+		description: fmt.Sprintf("shorter than %d half-moves", min),
+		accept: func(g *game.Game) bool {
+			return gen.GameLength(g) >= min
+		},
+	}
+}
+
+// materialSwingFilter returns filter accepting only games, whose absolute material balance never exceeds max centipawns, or nil if max is not positive.
+// Generated games exceeding it are abandoned during generation, so the filter discards games loaded from storage.
+func materialSwingFilter(max int) *gameFilter {
+	if max <= 0 {
+		return nil
+	}
+	return &gameFilter{
+		description: fmt.Sprintf("loaded from storage with material swing over %d pawns", max/100),
+		accept: func(g *game.Game) bool {
+			rg, err := withPositions(g)
+			if err != nil {
+				log.Printf("Error replaying stored game #%s: %v", g.Tags["#"], err)
+				return false
+			}
+			for _, b := range gen.MaterialBalances(rg) {
+				if b > max || -b > max {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package gen
+
+import (
+	"errors"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/square"
+)
+
+// ErrBailed is the cause of the error returned by Generate, when the game was abandoned, because it would end shorter than Options.BailBelow.
+var ErrBailed = errors.New("gen: game bailed out, it would be too short")
+
+// MaxRemainingHalfMoves returns an upper bound of the number of half-moves, which can still be played from the position.
+// Every capture and pawn move resets the fifty-move rule and there can be at most one capture for every piece on board besides kings
+// and as many pawn moves as there are ranks in front of pawns, so the game ends at latest fiftyMoveHalfMoves half-moves after the last of them.
+// The bound is loose, it is useful late in games with little material.
+func MaxRemainingHalfMoves(pos *position.Position) int {
+	irreversible := 0
+	for i := 0; i < 64; i++ {
+		p := pos.OnSquare(square.Square(i))
+		switch p.Type {
+		case piece.None, piece.King:
+			continue
+		case piece.Pawn:
+			// A pawn can move at most to the last rank.
+			_, rank := fileRank(square.Square(i))
+			if p.Color == piece.White {
+				irreversible += 7 - rank
+			} else {
+				irreversible += rank
+			}
+		}
+		irreversible += 1
+	}
+	remaining := fiftyMoveHalfMoves - int(pos.FiftyMoveCount)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining + irreversible*fiftyMoveHalfMoves
+}
+
+// Reports whether the game can't reach minLength half-moves anymore.
+func tooShort(ply int, pos *position.Position, minLength int) bool {
+	if ply >= minLength {
+		return false
+	}
+	return ply+MaxRemainingHalfMoves(pos) < minLength
+}
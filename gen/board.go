@@ -0,0 +1,43 @@
+package gen
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+)
+
+// Letters of pieces in PrintBoard, uppercase for White and lowercase for Black.
+var pieceLetters = map[piece.Type]string{
+	piece.Pawn:   "P",
+	piece.Knight: "N",
+	piece.Bishop: "B",
+	piece.Rook:   "R",
+	piece.Queen:  "Q",
+	piece.King:   "K",
+}
+
+// PrintBoard writes the board of the position to w as 8 ranks from White's perspective, with piece letters
+// (uppercase for White, lowercase for Black), dots for empty squares and rank and file labels.
+func PrintBoard(w io.Writer, pos *position.Position) error {
+	b := strings.Builder{}
+	for rank := 7; rank >= 0; rank-- {
+		fmt.Fprintf(&b, "%d ", rank+1)
+		for file := 0; file < 8; file++ {
+			p := pos.OnSquare(squareAt(file, rank))
+			letter, ok := pieceLetters[p.Type]
+			if !ok {
+				letter = "."
+			} else if p.Color == piece.Black {
+				letter = strings.ToLower(letter)
+			}
+			b.WriteString(" " + letter)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("   a b c d e f g h\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,75 @@
+package gen
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// Chess960Standard is the ID of the standard chess starting arrangement among Chess960 starting positions.
+const Chess960Standard = 518
+
+// Knight placements on the five squares left after placing bishops and queen, indexed by Scharnagl numbering.
+var chess960Knights = [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}
+
+// Chess960FEN returns FEN of the Chess960 starting position with positionID (0-959) in Scharnagl numbering.
+// The underlying chess library only knows castling with king on the e-file and rooks in the corners,
+// so castling rights are kept only for the standard arrangement (Chess960Standard) and not granted for other arrangements.
+func Chess960FEN(positionID int) (string, error) {
+	if positionID < 0 || positionID >= 960 {
+		return "", fmt.Errorf("gen: Chess960 position ID %d out of range 0-959", positionID)
+	}
+	rank := make([]byte, 8)
+	n := positionID
+	rank[2*(n%4)+1] = 'B'
+	n /= 4
+	rank[2*(n%4)] = 'B'
+	n /= 4
+	// Returns index of the i-th empty square in rank.
+	empty := func(i int) int {
+		for f := range rank {
+			if rank[f] == 0 {
+				if i == 0 {
+					return f
+				}
+				i -= 1
+			}
+		}
+		return -1
+	}
+	rank[empty(n%6)] = 'Q'
+	n /= 6
+	knights := chess960Knights[n]
+	// Placing the first knight shifts the empty squares, so the second knight is placed first.
+	rank[empty(knights[1])] = 'N'
+	rank[empty(knights[0])] = 'N'
+	rank[empty(0)] = 'R'
+	rank[empty(0)] = 'K'
+	rank[empty(0)] = 'R'
+
+	castling := "-"
+	if positionID == Chess960Standard {
+		castling = "KQkq"
+	}
+	white := string(rank)
+	return fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w %s - 0 1", strings.ToLower(white), white, castling), nil
+}
+
+// GenerateChess960 plays random legal moves from the Chess960 starting position with positionID (see Chess960FEN) until the game ends.
+// If positionID is negative, the starting position is derived from the seed.
+// The starting FEN is stored in the "FEN" tag of the returned game.
+//
+// Castling is limited by the chess library: games from arrangements other than Chess960Standard start without castling rights,
+// so they never castle and their SAN moves need no Chess960 castling notation. They are Chess960 games without castling.
+func GenerateChess960(positionID int, seed int64) (*game.Game, error) {
+	if positionID < 0 {
+		positionID = rand.New(rand.NewSource(seed)).Intn(960)
+	}
+	startFEN, err := Chess960FEN(positionID)
+	if err != nil {
+		return nil, err
+	}
+	return Generate(seed, Options{FEN: startFEN})
+}
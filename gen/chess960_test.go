@@ -0,0 +1,76 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChess960FEN(t *testing.T) {
+	for _, c := range []struct {
+		id   int
+		want string
+	}{
+		{0, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w - - 0 1"},
+		{Chess960Standard, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{959, "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w - - 0 1"},
+	} {
+		got, err := Chess960FEN(c.id)
+		if err != nil {
+			t.Fatalf("position %d: %v", c.id, err)
+		}
+		if got != c.want {
+			t.Errorf("position %d has FEN %q, want %q", c.id, got, c.want)
+		}
+	}
+	seen := map[string]bool{}
+	for id := 0; id < 960; id += 1 {
+		s, err := Chess960FEN(id)
+		if err != nil {
+			t.Fatalf("position %d: %v", id, err)
+		}
+		rank := strings.Split(s, "/")[7][:8]
+		if seen[rank] {
+			t.Errorf("position %d repeats arrangement %s", id, rank)
+		}
+		seen[rank] = true
+		king, rooks, bishops := strings.IndexByte(rank, 'K'), []int{}, 0
+		for f := range rank {
+			switch rank[f] {
+			case 'R':
+				rooks = append(rooks, f)
+			case 'B':
+				bishops += f
+			}
+		}
+		if len(rooks) != 2 || king < rooks[0] || king > rooks[1] || bishops%2 == 0 {
+			t.Errorf("position %d has invalid arrangement %s", id, rank)
+		}
+	}
+	for _, id := range []int{-1, 960} {
+		if _, err := Chess960FEN(id); err == nil {
+			t.Errorf("position %d: expected error", id)
+		}
+	}
+}
+
+func TestGenerateChess960(t *testing.T) {
+	g, err := GenerateChess960(0, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := Chess960FEN(0); g.Tags[TagFEN] != want {
+		t.Errorf("game has FEN tag %q, want %q", g.Tags[TagFEN], want)
+	}
+	for _, san := range SANMoves(g) {
+		if strings.HasPrefix(san, "O-O") {
+			t.Errorf("game from arrangement without castling rights castles with %s", san)
+		}
+	}
+	derived, err := GenerateChess960(-1, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if derived.Tags[TagFEN] == "" {
+		t.Errorf("game with position derived from seed has no FEN tag")
+	}
+}
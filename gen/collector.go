@@ -0,0 +1,352 @@
+package gen
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// LengthCollector stores games with half-moves closest to target values.
+//
+// Every target is tracked independently, so one game can be the closest game for several targets at once.
+// In that case the same *game.Game is returned for each of those targets.
+// When a game is as far from the target as the currently stored one, the tie is broken by the TieBreak policy (see SetTieBreak).
+// By default the game with lower seed wins, which is the first seen game when games are added in the order of seeds.
+// Games without a seed tag never replace an equally distant stored game, unless the policy prefers them, so the first seen game is kept.
+//
+// Targets set as exact (see SetExact) accept only games with exactly the target number of half-moves and stay unfilled until such game is added.
+//
+// Besides targets, the collector can keep the longest game, the shortest decisive game and the longest game ending by checkmate
+// (see SetLongest, SetShortest and SetLongestMate).
+//
+// Percentile targets (see SetPercentiles) are known only after all games are added, so they become targets after ResolvePercentiles is called.
+type LengthCollector struct {
+	gamesOfLength map[int]*game.Game
+	exact         map[int]bool
+	tieBreak      TieBreak
+
+	keepLongest, keepShortest, keepLongestMate bool
+	longest, shortest, longestMate             *game.Game
+
+	percentiles []int
+	// Number of added games and the game with the lowest seed for every length, kept only for percentile targets.
+	lengthCounts map[int]int
+	byLength     map[int]*game.Game
+}
+
+// NewLengthCollector returns a collector for provided half-move targets.
+// Duplicate targets are collected only once.
+func NewLengthCollector(targets []int) *LengthCollector {
+	c := &LengthCollector{
+		gamesOfLength: make(map[int]*game.Game, len(targets)),
+		exact:         map[int]bool{},
+	}
+	for _, t := range targets {
+		c.gamesOfLength[t] = nil
+	}
+	return c
+}
+
+// GameLength returns number of half-moves in the game.
+// Games with 0 positions are from storage and the length is stored in capacity of Game.Positions slice.
+func GameLength(g *game.Game) int {
+	if len(g.Positions) == 0 {
+		return cap(g.Positions) - 1
+	}
+	return len(g.Positions) - 1
+}
+
+func dist(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// GameSeed returns the seed stored in the "#" tag of the game.
+func GameSeed(g *game.Game) (int64, bool) {
+	seed, err := strconv.ParseInt(g.Tags["#"], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return seed, true
+}
+
+// Reports whether both games have seeds and seed of a is lower than seed of b.
+func seedLess(a, b *game.Game) bool {
+	as, aok := GameSeed(a)
+	bs, bok := GameSeed(b)
+	return aok && bok && as < bs
+}
+
+// Add offers the game to every target and keeps it for those targets, where it is closer than the stored game.
+// The longest game, the shortest decisive game and the longest mate are updated too, if they are kept, with the lower seed winning ties.
+// Among games of the same length, the one kept for percentile targets wins ties by the tie break policy.
+func (c *LengthCollector) Add(g *game.Game) {
+	n := GameLength(g)
+	if len(c.percentiles) > 0 {
+		c.lengthCounts[n] += 1
+		if lg := c.byLength[n]; lg == nil || c.tieLess(g, lg) {
+			c.byLength[n] = g
+		}
+	}
+	if c.keepLongest {
+		if c.longest == nil || n > GameLength(c.longest) || n == GameLength(c.longest) && seedLess(g, c.longest) {
+			c.longest = g
+		}
+	}
+	if c.keepLongestMate && IsDecisive(g) {
+		if c.longestMate == nil || n > GameLength(c.longestMate) || n == GameLength(c.longestMate) && seedLess(g, c.longestMate) {
+			c.longestMate = g
+		}
+	}
+	if c.keepShortest && IsDecisive(g) {
+		if c.shortest == nil || n < GameLength(c.shortest) || n == GameLength(c.shortest) && seedLess(g, c.shortest) {
+			c.shortest = g
+		}
+	}
+	for l, lg := range c.gamesOfLength {
+		if c.exact[l] && n != l {
+			continue
+		}
+		if lg == nil {
+			c.gamesOfLength[l] = g
+			continue
+		}
+		lgn := GameLength(lg)
+		if d, ld := dist(l, n), dist(l, lgn); d < ld || d == ld && c.tieLess(g, lg) {
+			c.gamesOfLength[l] = g
+		}
+	}
+}
+
+// TieBreak is a policy choosing between two games equally distant from a target.
+type TieBreak int
+
+// Tie break policies. Games equal by the policy are decided by lower seed.
+const (
+	// TieBreakSeed prefers the game with lower seed. It is the default.
+	TieBreakSeed TieBreak = iota
+	// TieBreakShorter prefers the shorter game, i.e. the one below the target.
+	TieBreakShorter
+	// TieBreakLonger prefers the longer game, i.e. the one above the target.
+	TieBreakLonger
+	// TieBreakCaptures prefers the game with more captures (see GameCounts).
+	TieBreakCaptures
+)
+
+// SetTieBreak sets the policy choosing between games equally distant from a target. It has to be set before games are added.
+func (c *LengthCollector) SetTieBreak(tb TieBreak) {
+	c.tieBreak = tb
+}
+
+// Reports whether game a wins the tie with equally distant game b by the tie break policy.
+func (c *LengthCollector) tieLess(a, b *game.Game) bool {
+	switch c.tieBreak {
+	case TieBreakShorter:
+		if la, lb := GameLength(a), GameLength(b); la != lb {
+			return la < lb
+		}
+	case TieBreakLonger:
+		if la, lb := GameLength(a), GameLength(b); la != lb {
+			return la > lb
+		}
+	case TieBreakCaptures:
+		ca, _ := GameCounts(a)
+		cb, _ := GameCounts(b)
+		if ca != cb {
+			return ca > cb
+		}
+	}
+	return seedLess(a, b)
+}
+
+// Targets returns collected targets in ascending order.
+func (c *LengthCollector) Targets() []int {
+	targets := make([]int, 0, len(c.gamesOfLength))
+	for l := range c.gamesOfLength {
+		targets = append(targets, l)
+	}
+	sort.Ints(targets)
+	return targets
+}
+
+// Game returns the game closest to the target, or nil if no game was added yet.
+func (c *LengthCollector) Game(target int) *game.Game {
+	return c.gamesOfLength[target]
+}
+
+// SetExact sets whether the target accepts only games with exactly target half-moves.
+// It has to be set before games are added. Unknown targets are ignored.
+func (c *LengthCollector) SetExact(target int, exact bool) {
+	if _, ok := c.gamesOfLength[target]; ok {
+		c.exact[target] = exact
+	}
+}
+
+// Exact reports whether the target accepts only games with exactly target half-moves.
+func (c *LengthCollector) Exact(target int) bool {
+	return c.exact[target]
+}
+
+// Unfilled returns targets without a game in ascending order.
+func (c *LengthCollector) Unfilled() []int {
+	unfilled := []int{}
+	for _, l := range c.Targets() {
+		if c.gamesOfLength[l] == nil {
+			unfilled = append(unfilled, l)
+		}
+	}
+	return unfilled
+}
+
+// FarFromTarget returns targets in ascending order, whose game length differs from the target by more than threshold times the target.
+// E.g. with threshold 0.5, a game with 120 half-moves is far from target 750. Targets without a game are not returned.
+func (c *LengthCollector) FarFromTarget(threshold float64) []int {
+	far := []int{}
+	for _, l := range c.Targets() {
+		g := c.gamesOfLength[l]
+		if g == nil {
+			continue
+		}
+		if d := math.Abs(float64(GameLength(g) - l)); d > threshold*float64(l) {
+			far = append(far, l)
+		}
+	}
+	return far
+}
+
+// SetLongest sets whether the collector keeps the longest added game.
+func (c *LengthCollector) SetLongest(keep bool) {
+	c.keepLongest = keep
+}
+
+// SetShortest sets whether the collector keeps the shortest added decisive game (see IsDecisive).
+func (c *LengthCollector) SetShortest(keep bool) {
+	c.keepShortest = keep
+}
+
+// SetLongestMate sets whether the collector keeps the longest added game ending by checkmate.
+// Only checkmate decides games (see IsDecisive), so it is the longest decisive game.
+func (c *LengthCollector) SetLongestMate(keep bool) {
+	c.keepLongestMate = keep
+}
+
+// LongestMate returns the longest added game ending by checkmate, or nil if it is not kept or no such game was added.
+func (c *LengthCollector) LongestMate() *game.Game {
+	return c.longestMate
+}
+
+// Longest returns the longest added game, or nil if it is not kept or no game was added.
+func (c *LengthCollector) Longest() *game.Game {
+	return c.longest
+}
+
+// Shortest returns the shortest added decisive game, or nil if it is not kept or no decisive game was added.
+func (c *LengthCollector) Shortest() *game.Game {
+	return c.shortest
+}
+
+// SetPercentiles sets percentiles of lengths of added games, e.g. 25 for the 25th percentile, which become targets after ResolvePercentiles is called.
+// It has to be set before games are added.
+func (c *LengthCollector) SetPercentiles(percentiles []int) {
+	c.percentiles = percentiles
+	c.lengthCounts = map[int]int{}
+	c.byLength = map[int]*game.Game{}
+}
+
+// ResolvePercentiles computes lengths of percentiles of all added games using the nearest-rank method, adds them as targets
+// and fills them with the closest added game. It returns the target length for every percentile, none if no game was added.
+// It has to be called after all games are added, games added afterwards are not counted for percentiles.
+func (c *LengthCollector) ResolvePercentiles() map[int]int {
+	resolved := map[int]int{}
+	total := 0
+	lengths := make([]int, 0, len(c.lengthCounts))
+	for l, n := range c.lengthCounts {
+		total += n
+		lengths = append(lengths, l)
+	}
+	if total == 0 {
+		return resolved
+	}
+	sort.Ints(lengths)
+	for _, p := range c.percentiles {
+		rank := int(math.Ceil(float64(p) / 100 * float64(total)))
+		if rank < 1 {
+			rank = 1
+		}
+		seen := 0
+		for _, l := range lengths {
+			seen += c.lengthCounts[l]
+			if seen >= rank {
+				resolved[p] = l
+				break
+			}
+		}
+	}
+	for _, l := range resolved {
+		if _, ok := c.gamesOfLength[l]; !ok {
+			// Percentile length is a length of an added game, so it is the closest game.
+			c.gamesOfLength[l] = c.byLength[l]
+		}
+	}
+	return resolved
+}
+
+// MinUseful returns the lowest length of a game, which can still replace a stored game of any target, the longest game or the longest mate.
+// Games shorter than that can't change what the collector selects. If the shortest decisive game is kept, or there are percentile targets, any game can be useful and 0 is returned.
+func (c *LengthCollector) MinUseful() int {
+	if c.keepShortest || len(c.percentiles) > 0 {
+		return 0
+	}
+	min := math.MaxInt32
+	if c.keepLongest {
+		min = 0
+		if c.longest != nil {
+			min = GameLength(c.longest)
+		}
+	}
+	if c.keepLongestMate {
+		useful := 0
+		if c.longestMate != nil {
+			useful = GameLength(c.longestMate)
+		}
+		if useful < min {
+			min = useful
+		}
+	}
+	for l, g := range c.gamesOfLength {
+		useful := l
+		if g == nil {
+			if !c.exact[l] {
+				useful = 0
+			}
+		} else if c.exact[l] {
+			continue
+		} else {
+			// Equally distant game with lower seed can replace the stored one, so the distance is included.
+			useful = l - dist(l, GameLength(g))
+		}
+		if useful < min {
+			min = useful
+		}
+	}
+	return min
+}
+
+// HasPercentiles reports whether the collector has percentile targets (see SetPercentiles).
+func (c *LengthCollector) HasPercentiles() bool {
+	return len(c.percentiles) > 0
+}
+
+// HasExact reports whether any target accepts only games with exactly target half-moves.
+func (c *LengthCollector) HasExact() bool {
+	for _, exact := range c.exact {
+		if exact {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package gen
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/position"
+)
+
+// loadedGame returns a game like one loaded from storage, with the seed, n half-moves and the number of captures.
+func loadedGame(seed int64, n, captures int) *game.Game {
+	return &game.Game{
+		Tags:      map[string]string{"#": fmt.Sprint(seed), TagCaptures: fmt.Sprint(captures), TagChecks: "0"},
+		Positions: make([]*position.Position, 0, n+1),
+	}
+}
+
+func TestResolvePercentilesTieBreak(t *testing.T) {
+	for _, c := range []struct {
+		tb   TieBreak
+		want string
+	}{
+		{TieBreakSeed, "1"},
+		{TieBreakCaptures, "2"},
+	} {
+		lc := NewLengthCollector(nil)
+		lc.SetTieBreak(c.tb)
+		lc.SetPercentiles([]int{50})
+		lc.Add(loadedGame(1, 10, 0))
+		lc.Add(loadedGame(2, 10, 3))
+		lc.Add(loadedGame(3, 20, 5))
+		resolved := lc.ResolvePercentiles()
+		if resolved[50] != 10 {
+			t.Fatalf("50th percentile is %d, want 10", resolved[50])
+		}
+		if got := lc.Game(10).Tags["#"]; got != c.want {
+			t.Errorf("tie break %v: 50th percentile game has seed #%s, want #%s", c.tb, got, c.want)
+		}
+	}
+}
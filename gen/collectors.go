@@ -0,0 +1,80 @@
+package gen
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// CollectorSet offers every added game to multiple named collectors, so different datasets can be collected in one pass over seeds.
+type CollectorSet struct {
+	names      []string
+	collectors map[string]*LengthCollector
+}
+
+// NewCollectorSet returns an empty collector set.
+func NewCollectorSet() *CollectorSet {
+	return &CollectorSet{
+		collectors: map[string]*LengthCollector{},
+	}
+}
+
+// Register adds the collector under the name. Names have to be unique.
+func (s *CollectorSet) Register(name string, c *LengthCollector) error {
+	if _, ok := s.collectors[name]; ok {
+		return fmt.Errorf("gen: collector %q already registered", name)
+	}
+	s.names = append(s.names, name)
+	s.collectors[name] = c
+	return nil
+}
+
+// Names returns names of registered collectors in order of registration.
+func (s *CollectorSet) Names() []string {
+	return append([]string(nil), s.names...)
+}
+
+// Collector returns the collector registered under the name, or nil.
+func (s *CollectorSet) Collector(name string) *LengthCollector {
+	return s.collectors[name]
+}
+
+// Add offers the game to all registered collectors.
+func (s *CollectorSet) Add(g *game.Game) {
+	for _, name := range s.names {
+		s.collectors[name].Add(g)
+	}
+}
+
+// ExactFilled reports whether some collector has exact targets and all exact targets of all collectors are filled.
+// It is never true, if some collector has percentile targets, which need all games.
+func (s *CollectorSet) ExactFilled() bool {
+	hasExact := false
+	for _, c := range s.collectors {
+		if c.HasPercentiles() {
+			return false
+		}
+		if !c.HasExact() {
+			continue
+		}
+		hasExact = true
+		for _, l := range c.Unfilled() {
+			if c.Exact(l) {
+				return false
+			}
+		}
+	}
+	return hasExact
+}
+
+// MinUseful returns the lowest length of a game, which can change what any of the collectors selects (see LengthCollector.MinUseful).
+func (s *CollectorSet) MinUseful() int {
+	min := math.MaxInt32
+	for _, c := range s.collectors {
+		if m := c.MinUseful(); m < min {
+			min = m
+		}
+	}
+	return min
+}
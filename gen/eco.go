@@ -0,0 +1,67 @@
+package gen
+
+import (
+	_ "embed"
+	"strings"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// Table of openings with tab separated ECO code, name and SAN moves from the initial position.
+//
+//go:embed eco.tsv
+var ecoTable string
+
+// ecoOpening is an opening from ecoTable.
+type ecoOpening struct {
+	eco, name string
+	moves     []string
+}
+
+// Openings parsed from ecoTable.
+var ecoOpenings = parseECOTable(ecoTable)
+
+func parseECOTable(table string) []ecoOpening {
+	openings := []ecoOpening{}
+	for _, line := range strings.Split(table, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		openings = append(openings, ecoOpening{fields[0], fields[1], strings.Fields(fields[2])})
+	}
+	return openings
+}
+
+// ClassifyOpening returns ECO code and name of the opening of the game, which is the known opening with the longest sequence of moves the game starts with.
+// Known openings cover every first move, so only games not starting from the initial position, or without any move, return empty code and name.
+func ClassifyOpening(g *game.Game) (eco string, name string) {
+	if _, ok := g.Tags[TagFEN]; ok {
+		return "", ""
+	}
+	var sanMoves []string
+	if len(g.Positions) == 0 {
+		sanMoves = strings.Fields(g.Tags["sanMoves"])
+	} else {
+		sanMoves = SANMoves(g)
+	}
+	best := -1
+	for _, o := range ecoOpenings {
+		if len(o.moves) <= best || len(o.moves) > len(sanMoves) {
+			continue
+		}
+		matches := true
+		for i, m := range o.moves {
+			// Openings in the table have no checks, but SAN moves of the game may have them.
+			if strings.TrimRight(sanMoves[i], "+#") != m {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			best = len(o.moves)
+			eco, name = o.eco, o.name
+		}
+	}
+	return eco, name
+}
@@ -0,0 +1,56 @@
+package gen
+
+import (
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// Engine implements chess rules for the generation loop, so a different move generator can replace the default one.
+// Moves, positions and statuses use types of github.com/andrewbackes/chess, which are also used in generated games.
+type Engine interface {
+	// LegalMoves returns legal moves in the current position in any order.
+	LegalMoves() []move.Move
+	// MakeMove plays the move and returns status of the game after it.
+	MakeMove(m move.Move) (game.GameStatus, error)
+	// Status returns the status of the game in the current position.
+	Status() game.GameStatus
+	// Position returns the current position.
+	Position() *position.Position
+	// Ply returns the number of half-moves played since the starting position.
+	Ply() int
+}
+
+// NewGameEngine returns the default engine, which plays moves in the game using github.com/andrewbackes/chess.
+func NewGameEngine(g *game.Game) Engine {
+	return gameEngine{g}
+}
+
+// gameEngine is an Engine adapter for *game.Game.
+type gameEngine struct {
+	g *game.Game
+}
+
+func (e gameEngine) LegalMoves() []move.Move {
+	moves := []move.Move{}
+	for m := range e.g.LegalMoves() {
+		moves = append(moves, m)
+	}
+	return moves
+}
+
+func (e gameEngine) MakeMove(m move.Move) (game.GameStatus, error) {
+	return e.g.MakeMove(m)
+}
+
+func (e gameEngine) Status() game.GameStatus {
+	return e.g.Status()
+}
+
+func (e gameEngine) Position() *position.Position {
+	return e.g.Positions[len(e.g.Positions)-1]
+}
+
+func (e gameEngine) Ply() int {
+	return len(e.g.Positions) - 1
+}
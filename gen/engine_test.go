@@ -0,0 +1,35 @@
+package gen
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// noMovesEngine is an engine, which reports the game in progress, but has no legal moves.
+type noMovesEngine struct {
+	Engine
+}
+
+func (noMovesEngine) LegalMoves() []move.Move {
+	return nil
+}
+
+func TestGenerateNoLegalMoves(t *testing.T) {
+	opts := Options{Engine: func(g *game.Game) Engine {
+		return noMovesEngine{NewGameEngine(g)}
+	}}
+	_, err := Generate(42, opts)
+	if !errors.Is(err, ErrNoLegalMoves) {
+		t.Fatalf("generating with engine without legal moves returned %v, want %v", err, ErrNoLegalMoves)
+	}
+	ge := &GenerationError{}
+	if !errors.As(err, &ge) {
+		t.Fatalf("error %v is not a *GenerationError", err)
+	}
+	if ge.Seed != 42 || ge.Ply != 0 || ge.FEN == "" {
+		t.Errorf("generation error has seed %d, ply %d and FEN %q, want seed 42, ply 0 and FEN of the initial position", ge.Seed, ge.Ply, ge.FEN)
+	}
+}
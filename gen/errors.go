@@ -0,0 +1,46 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// Causes of generation failures, which can be tested with errors.Is on errors returned by Generate and related functions.
+var (
+	// ErrIllegalMove is the cause of failures, when the engine refused to play the picked move.
+	ErrIllegalMove = errors.New("gen: illegal move")
+	// ErrInvalidFEN is the cause of failures, when the starting FEN is not valid.
+	ErrInvalidFEN = errors.New("gen: invalid FEN")
+	// ErrMaterialSwing is the cause of failures, when the material balance exceeded Options.MaxMaterialSwing.
+	ErrMaterialSwing = errors.New("gen: material balance exceeded maximal swing")
+	// ErrNoLegalMoves is the cause of failures, when there is no legal move in a position of a game in progress.
+	ErrNoLegalMoves = errors.New("gen: no legal moves in game in progress")
+)
+
+// GenerationError is returned when generation of the game with the seed failed after ply half-moves.
+// Move is the move being played, or move.Null if the failure happened before a move was picked, and FEN is the position, if known.
+// Err is the cause, e.g. ErrIllegalMove, ErrInvalidFEN, ErrNoLegalMoves, ErrMaterialSwing, ErrBailed or the error of the cancelled context.
+type GenerationError struct {
+	Seed int64
+	Ply  int
+	Move move.Move
+	FEN  string
+	Err  error
+}
+
+func (e *GenerationError) Error() string {
+	s := fmt.Sprintf("%v (game #%d, ply %d", e.Err, e.Seed, e.Ply)
+	if e.Move != move.Null {
+		s += fmt.Sprintf(", move %v", e.Move)
+	}
+	if e.FEN != "" {
+		s += fmt.Sprintf(", position %q", e.FEN)
+	}
+	return s + ")"
+}
+
+func (e *GenerationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,95 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// Maximal number of seeds scanned by GenerateExactLength.
+const exactLengthSeedLimit = 10000000
+
+// Number of seeds generated in parallel at once by GenerateExactLength.
+const exactLengthBatch = 1000
+
+// Returned from GenerateParallel callback to stop generation when enough games were found.
+var errEnough = errors.New("gen: enough games")
+
+// GenerateExactLength scans seeds from startSeed upwards until it finds n distinct games (see GameHash) of exactly length half-moves,
+// and returns them in the order of seeds. Seeds of the games are in their "#" tags.
+// Games are stopped as soon as they are longer than length or can't reach it (see Options.BailBelow), so they are cheap to reject.
+// An error is returned if fewer games are found in the first exactLengthSeedLimit seeds.
+func GenerateExactLength(n, length int, startSeed int64) ([]*game.Game, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("gen: length can't be negative, got %d", length)
+	}
+	opts := Options{
+		MaxHalfMoves: length + 1,
+		BailBelow:    func() int { return length },
+	}
+	games := []*game.Game{}
+	seen := map[string]bool{}
+	for next := startSeed; next-startSeed < exactLengthSeedLimit; next += exactLengthBatch {
+		batch := make([]int64, 0, exactLengthBatch)
+		for seed := next; seed < next+exactLengthBatch && seed-startSeed < exactLengthSeedLimit; seed += 1 {
+			batch = append(batch, seed)
+		}
+		err := GenerateParallel(context.Background(), batch, runtime.GOMAXPROCS(0), opts, func(r GameResult) error {
+			if errors.Is(r.Err, ErrBailed) {
+				return nil
+			}
+			if r.Err != nil {
+				return fmt.Errorf("gen: generating game with seed #%d: %w", r.Seed, r.Err)
+			}
+			if IsTruncated(r.Game) || GameLength(r.Game) != length {
+				return nil
+			}
+			h := GameHash(r.Game)
+			if seen[h] {
+				return nil
+			}
+			seen[h] = true
+			games = append(games, r.Game)
+			if len(games) == n {
+				return errEnough
+			}
+			return nil
+		})
+		if err == errEnough {
+			return games, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("gen: found only %d of %d games of length %d in %d seeds from #%d", len(games), n, length, exactLengthSeedLimit, startSeed)
+}
+
+// FindSeedForLength scans seeds from 0 to maxSeed and returns the first seed, whose game (see GenerateRandomGame) has exactly length half-moves.
+// Games are stopped as soon as they are longer than length or can't reach it, like in GenerateExactLength.
+// It returns false if no such seed is found.
+func FindSeedForLength(length int, maxSeed int64) (int64, bool) {
+	if length < 0 {
+		return 0, false
+	}
+	opts := Options{
+		MaxHalfMoves: length + 1,
+		BailBelow:    func() int { return length },
+	}
+	for seed := int64(0); seed <= maxSeed; seed += 1 {
+		g, err := Generate(seed, opts)
+		if err != nil {
+			continue
+		}
+		if !IsTruncated(g) && GameLength(g) == length {
+			return seed, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,136 @@
+package gen
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+)
+
+// TagFEN is a game tag holding FEN of the starting position for games not starting from the initial position.
+const TagFEN = "FEN"
+
+// FinalFEN returns FEN of the last position of the game, including castling rights, en passant square and halfmove clock at the end of the game.
+// Empty string is returned for games without positions (e.g. loaded from storage) or if the position can't be encoded.
+func FinalFEN(g *game.Game) string {
+	if len(g.Positions) == 0 {
+		return ""
+	}
+	s, err := fen.Encode(g.Positions[len(g.Positions)-1])
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// AllFENs returns FEN of every position of the game, from the starting to the last one.
+// For long games prefer WriteFENs, which doesn't keep all FENs in memory.
+func AllFENs(g *game.Game) []string {
+	fens := make([]string, 0, len(g.Positions))
+	for _, pos := range g.Positions {
+		s, err := fen.Encode(pos)
+		if err != nil {
+			s = ""
+		}
+		fens = append(fens, s)
+	}
+	return fens
+}
+
+// WriteFENs writes FEN of every position of the game to w, one per line, encoding positions one at a time.
+func WriteFENs(w io.Writer, g *game.Game) error {
+	for i, pos := range g.Positions {
+		s, err := fen.Encode(pos)
+		if err != nil {
+			return fmt.Errorf("gen: encoding position %d: %v", i, err)
+		}
+		if _, err := io.WriteString(w, s+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FinalEPD returns EPD record of the last position of the game: the first four FEN fields (piece placement, active color, castling rights, en passant square)
+// followed by semicolon terminated operations hmvc (halfmove clock), fmvn (fullmove number) and c0 comment with the seed of the game.
+// Empty string is returned for games without positions (e.g. loaded from storage) or if the position can't be encoded.
+func FinalEPD(g *game.Game) string {
+	fields := strings.Fields(FinalFEN(g))
+	if len(fields) != 6 {
+		return ""
+	}
+	epd := fmt.Sprintf("%s hmvc %s; fmvn %s;", strings.Join(fields[:4], " "), fields[4], fields[5])
+	if seed, ok := g.Tags["#"]; ok {
+		epd += fmt.Sprintf(" c0 %q;", "seed "+seed)
+	}
+	return epd
+}
+
+// ValidateFEN checks the structure of all six FEN fields and returns an error describing the first problem found.
+// It doesn't check whether the position is reachable, only that it can be played from.
+func ValidateFEN(s string) error {
+	invalid := func(format string, a ...interface{}) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidFEN, s, fmt.Sprintf(format, a...))
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 6 {
+		return invalid("expected 6 fields, got %d", len(fields))
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return invalid("expected 8 ranks in piece placement, got %d", len(ranks))
+	}
+	kings := map[rune]int{}
+	for i, rank := range ranks {
+		files := 0
+		for _, c := range rank {
+			switch {
+			case c >= '1' && c <= '8':
+				files += int(c - '0')
+			case strings.ContainsRune("pnbrqkPNBRQK", c):
+				files += 1
+				if c == 'k' || c == 'K' {
+					kings[c] += 1
+				}
+				if (c == 'p' || c == 'P') && (i == 0 || i == 7) {
+					return invalid("pawn on rank %d", 8-i)
+				}
+			default:
+				return invalid("unexpected character %q in piece placement", c)
+			}
+		}
+		if files != 8 {
+			return invalid("rank %d has %d files", 8-i, files)
+		}
+	}
+	if kings['K'] != 1 || kings['k'] != 1 {
+		return invalid("expected one king for each side, got %d white and %d black", kings['K'], kings['k'])
+	}
+
+	if fields[1] != "w" && fields[1] != "b" {
+		return invalid("active color must be \"w\" or \"b\", got %q", fields[1])
+	}
+	if fields[2] != "-" {
+		for _, c := range fields[2] {
+			if !strings.ContainsRune("KQkq", c) || strings.Count(fields[2], string(c)) > 1 {
+				return invalid("malformed castling availability %q", fields[2])
+			}
+		}
+	}
+	if ep := fields[3]; ep != "-" {
+		if len(ep) != 2 || ep[0] < 'a' || ep[0] > 'h' || (ep[1] != '3' && ep[1] != '6') {
+			return invalid("malformed en passant square %q", ep)
+		}
+	}
+	if n, err := strconv.Atoi(fields[4]); err != nil || n < 0 {
+		return invalid("halfmove clock must be a non-negative number, got %q", fields[4])
+	}
+	if n, err := strconv.Atoi(fields[5]); err != nil || n < 1 {
+		return invalid("fullmove number must be a positive number, got %q", fields[5])
+	}
+	return nil
+}
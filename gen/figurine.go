@@ -0,0 +1,20 @@
+package gen
+
+import (
+	"strings"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// Replaces piece letters in SAN with figurines. Files are lowercase and castling uses "O", so only piece letters are replaced.
+var figurineReplacer = strings.NewReplacer("K", "♔", "Q", "♕", "R", "♖", "B", "♗", "N", "♘")
+
+// FigurineSAN returns moves of the game in figurine algebraic notation, which is SAN with piece letters replaced by Unicode figurines, e.g. "♘f3" or "exd8=♕+".
+// Pawn moves have no figurine and castling is written as in SAN.
+func FigurineSAN(g *game.Game) []string {
+	moves := SANMoves(g)
+	for i, san := range moves {
+		moves[i] = figurineReplacer.Replace(san)
+	}
+	return moves
+}
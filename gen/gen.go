@@ -0,0 +1,310 @@
+// Package gen generates reproducible random chess games from integer seeds.
+package gen
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// Options configures generation of games.
+// The zero value generates games the same way as GenerateRandomGame.
+type Options struct {
+	// Picker picks moves to play. If nil, UniformPicker is used.
+	Picker Picker
+	// WhitePicker and BlackPicker pick moves of White and Black, e.g. to play one side with a fixed strategy. If nil, Picker picks moves of the side.
+	WhitePicker, BlackPicker Picker
+	// MaxHalfMoves stops the game after this number of half-moves and marks it truncated. 0 means unlimited.
+	MaxHalfMoves int
+	// StopAtPly stops the game after this number of half-moves, e.g. for opening books, marks it truncated and sets TagStoppedAtPly.
+	// Unlike MaxHalfMoves, it is meant to be short, so nearly all games are truncated. 0 means not stopping.
+	StopAtPly int
+	// FEN of the starting position. If empty, the initial position is used.
+	FEN string
+	// StopOnInsufficientMaterial declares the game drawn as soon as neither side can checkmate (see IsInsufficientMaterial).
+	// Such games are adjudicated (see TagAdjudication), because the game status is not changed.
+	StopOnInsufficientMaterial bool
+	// AvoidRepetition removes moves leading to a position already seen twice in the game from moves offered to Picker,
+	// so games don't end by threefold repetition, unless there is no other legal move.
+	AvoidRepetition bool
+	// StopOnDeadDraw declares the game drawn as soon as the position is a dead draw (see IsDeadDraw).
+	// Such games are adjudicated (see TagAdjudication), because the game status is not changed.
+	StopOnDeadDraw bool
+	// DrawAfterQuiet declares the game drawn after this number of half-moves without a capture or a pawn move, i.e. when the halfmove clock reaches it.
+	// Such games are adjudicated (see TagAdjudication) with DrawNoProgress. Values of 100 or more don't stop games sooner than the fifty-move rule. 0 means not adjudicating.
+	DrawAfterQuiet int
+	// MaxMaterialSwing abandons generation with an error wrapping ErrMaterialSwing, as soon as the absolute material balance (see MaterialBalance)
+	// exceeds this number of centipawns, e.g. to generate games without big material imbalance. 0 means no limit.
+	MaxMaterialSwing int
+	// OnGame is called with every successfully generated game, e.g. to save it to another sink.
+	// GenerateParallel calls it in the order of seeds from the goroutine calling GenerateParallel, before delivering the result.
+	OnGame func(seed int64, g *game.Game)
+	// RandFactory returns the source of randomness for the game with seed, passed to Picker.
+	// If nil, DefaultRandFactory is used. Games reproduce from seeds only with the same factory.
+	RandFactory func(seed int64) *rand.Rand
+	// BailBelow returns the minimal useful length of games. If set, it is called before every move and when the game can't reach this length anymore
+	// (see MaxRemainingHalfMoves), generation is abandoned with an error wrapping ErrBailed. It may be called concurrently by GenerateParallel.
+	BailBelow func() int
+	// Engine returns the engine playing moves in the game. It has to keep positions of the game in sync with played moves.
+	// If nil, NewGameEngine is used.
+	Engine func(g *game.Game) Engine
+}
+
+// DefaultRandFactory returns math/rand generator seeded with seed. It is used for games in storage files.
+func DefaultRandFactory(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// TagTruncated is a game tag set to "true" for games stopped before they ended.
+const TagTruncated = "Truncated"
+
+// TagStoppedAtPly is a game tag holding the ply, after which the game was stopped by Options.StopAtPly.
+const TagStoppedAtPly = "StoppedAtPly"
+
+// IsTruncated reports whether the game was stopped before it ended.
+func IsTruncated(g *game.Game) bool {
+	return g.Tags[TagTruncated] == "true"
+}
+
+// Game tags with the number of captures, checks and promotions in the game, set by Generate.
+const (
+	TagCaptures   = "captures"
+	TagChecks     = "checks"
+	TagPromotions = "promotions"
+)
+
+// Game tags with the total and the highest number of legal moves in positions, where a move was played, set by Generate (see Branching).
+const (
+	TagLegalMoves    = "legalMoves"
+	TagMaxLegalMoves = "maxLegalMoves"
+)
+
+// GameCounts returns the number of captures and checks in the game from its tags, if present.
+// Otherwise they are counted from positions of the game (see Summarize).
+func GameCounts(g *game.Game) (captures, checks int) {
+	c, errCaptures := strconv.Atoi(g.Tags[TagCaptures])
+	ch, errChecks := strconv.Atoi(g.Tags[TagChecks])
+	if errCaptures == nil && errChecks == nil {
+		return c, ch
+	}
+	stats := Summarize(g)
+	return stats.Captures, stats.Checks
+}
+
+// GenerateRandomGame plays random legal moves from the initial position until the game ends.
+// Legal moves are sorted in canonical order (see MoveLess) before each pick, so the same seed always produces the same game.
+// The seed is stored in the "#" tag of the returned game.
+// If ctx is cancelled, generation stops promptly and an error wrapping ctx.Err() is returned.
+func GenerateRandomGame(ctx context.Context, seed int64) (*game.Game, error) {
+	return GenerateContext(ctx, seed, Options{})
+}
+
+// Generate plays legal moves chosen by opts.Picker from the starting position until the game ends,
+// or until opts.MaxHalfMoves is reached, when the game is marked truncated (see IsTruncated).
+// The seed is stored in the "#" tag of the returned game and the number of captures, checks and promotions in TagCaptures, TagChecks and TagPromotions tags.
+func Generate(seed int64, opts Options) (*game.Game, error) {
+	return GenerateContext(context.Background(), seed, opts)
+}
+
+// GenerateContext is like Generate, but stops promptly, when ctx is cancelled, and returns an error wrapping ctx.Err().
+// Errors of failed generation are *GenerationError.
+func GenerateContext(ctx context.Context, seed int64, opts Options) (*game.Game, error) {
+	g, err := newGame(opts.FEN)
+	if err != nil {
+		return nil, &GenerationError{Seed: seed, Move: move.Null, FEN: opts.FEN, Err: err}
+	}
+	g, err = play(ctx, g, seed, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.OnGame != nil {
+		opts.OnGame(seed, g)
+	}
+	return g, nil
+}
+
+// Returns a new game starting from the position given by FEN, or from the initial position if FEN is empty.
+func newGame(startFEN string) (*game.Game, error) {
+	g := game.New()
+	if startFEN == "" {
+		return g, nil
+	}
+	if err := ValidateFEN(startFEN); err != nil {
+		return nil, err
+	}
+	pos, err := fen.Decode(startFEN)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidFEN, startFEN, err)
+	}
+	g.Positions = []*position.Position{pos}
+	g.Tags[TagFEN] = startFEN
+	return g, nil
+}
+
+// GenerateFromFEN plays random legal moves from the position given by FEN until the game ends.
+// The FEN is validated first and a descriptive error is returned for malformed input.
+// The starting FEN is stored in the "FEN" tag of the returned game.
+func GenerateFromFEN(fenStr string, seed int64) (*game.Game, error) {
+	return Generate(seed, Options{FEN: fenStr})
+}
+
+// Plays moves in the game until it ends, is truncated or ctx is cancelled.
+func play(ctx context.Context, g *game.Game, seed int64, opts Options) (*game.Game, error) {
+	pick := opts.Picker
+	if pick == nil {
+		pick = UniformPicker
+	}
+	whitePick, blackPick := pick, pick
+	if opts.WhitePicker != nil {
+		whitePick = opts.WhitePicker
+	}
+	if opts.BlackPicker != nil {
+		blackPick = opts.BlackPicker
+	}
+	newEngine := opts.Engine
+	if newEngine == nil {
+		newEngine = NewGameEngine
+	}
+	e := newEngine(g)
+	gs, err := e.Status(), error(nil)
+	g.Tags["#"] = fmt.Sprint(seed)
+	newRand := opts.RandFactory
+	if newRand == nil {
+		newRand = DefaultRandFactory
+	}
+	rnd := newRand(seed)
+	captures, checks, promotions := 0, 0, 0
+	legalMoves, maxLegalMoves := 0, 0
+	// Number of occurrences of positions in the game, counted only if repetitions are avoided.
+	seen := map[uint64]int{}
+	if opts.AvoidRepetition {
+		for _, pos := range g.Positions {
+			seen[PositionHash(pos)] += 1
+		}
+	}
+	if opts.MaxMaterialSwing > 0 && exceedsSwing(e.Position(), opts.MaxMaterialSwing) {
+		return nil, &GenerationError{Seed: seed, Ply: e.Ply(), Move: move.Null, Err: ErrMaterialSwing}
+	}
+	for gs == game.InProgress {
+		pos, ply := e.Position(), e.Ply()
+		if err := ctx.Err(); err != nil {
+			return nil, &GenerationError{Seed: seed, Ply: ply, Move: move.Null, Err: err}
+		}
+		if opts.BailBelow != nil && tooShort(ply, pos, opts.BailBelow()) {
+			return nil, &GenerationError{Seed: seed, Ply: ply, Move: move.Null, Err: ErrBailed}
+		}
+		if opts.StopOnInsufficientMaterial && IsInsufficientMaterial(pos) {
+			g.Tags[TagAdjudication] = DrawInsufficientMaterial
+			break
+		}
+		if opts.StopOnDeadDraw && IsDeadDraw(pos) {
+			g.Tags[TagAdjudication] = DrawDeadPosition
+			break
+		}
+		if opts.DrawAfterQuiet > 0 && pos.FiftyMoveCount >= uint64(opts.DrawAfterQuiet) {
+			g.Tags[TagAdjudication] = DrawNoProgress
+			break
+		}
+		if opts.StopAtPly > 0 && ply >= opts.StopAtPly {
+			g.Tags[TagTruncated] = "true"
+			g.Tags[TagStoppedAtPly] = fmt.Sprint(opts.StopAtPly)
+			break
+		}
+		if opts.MaxHalfMoves > 0 && ply >= opts.MaxHalfMoves {
+			g.Tags[TagTruncated] = "true"
+			break
+		}
+		movesSlice := e.LegalMoves()
+		if len(movesSlice) == 0 {
+			// Pickers can't pick from no moves, the engine status is inconsistent with the position.
+			s, _ := fen.Encode(pos)
+			return nil, &GenerationError{Seed: seed, Ply: ply, Move: move.Null, FEN: s, Err: ErrNoLegalMoves}
+		}
+		legalMoves += len(movesSlice)
+		if len(movesSlice) > maxLegalMoves {
+			maxLegalMoves = len(movesSlice)
+		}
+		SortMoves(movesSlice)
+
+		if opts.AvoidRepetition {
+			movesSlice = avoidRepetition(pos, movesSlice, seen)
+		}
+		sidePick := whitePick
+		if pos.ActiveColor == piece.Black {
+			sidePick = blackPick
+		}
+		m := sidePick(rnd, pos, movesSlice)
+		// Material changes only by captures and promotions.
+		materialChanged := false
+		if IsCapture(pos, m) {
+			captures += 1
+			materialChanged = true
+		}
+		if m.Promote != piece.None {
+			promotions += 1
+			materialChanged = true
+		}
+		gs, err = e.MakeMove(m)
+		if err != nil {
+			s, _ := fen.Encode(pos)
+			return nil, &GenerationError{Seed: seed, Ply: ply, Move: m, FEN: s, Err: fmt.Errorf("%w: %v", ErrIllegalMove, err)}
+		}
+		last := e.Position()
+		if last.Check(last.ActiveColor) {
+			checks += 1
+		}
+		if materialChanged && opts.MaxMaterialSwing > 0 && exceedsSwing(last, opts.MaxMaterialSwing) {
+			return nil, &GenerationError{Seed: seed, Ply: ply + 1, Move: m, Err: ErrMaterialSwing}
+		}
+		if opts.AvoidRepetition {
+			seen[PositionHash(last)] += 1
+		}
+	}
+	g.Tags[TagCaptures] = fmt.Sprint(captures)
+	g.Tags[TagChecks] = fmt.Sprint(checks)
+	g.Tags[TagPromotions] = fmt.Sprint(promotions)
+	g.Tags[TagLegalMoves] = fmt.Sprint(legalMoves)
+	g.Tags[TagMaxLegalMoves] = fmt.Sprint(maxLegalMoves)
+	return g, nil
+}
+
+// Reports whether the absolute material balance in the position exceeds max centipawns.
+func exceedsSwing(pos *position.Position, max int) bool {
+	b := MaterialBalance(pos)
+	return b > max || -b > max
+}
+
+// Returns moves not leading to a position seen at least twice, or all moves if all of them lead to such positions.
+// Order of moves is kept.
+func avoidRepetition(pos *position.Position, moves []move.Move, seen map[uint64]int) []move.Move {
+	allowed := make([]move.Move, 0, len(moves))
+	// Castling rights of the position are taken from FEN once, rights after candidate moves are derived from them.
+	castling := castlingRights(pos)
+	for _, m := range moves {
+		if seen[positionHash(pos.MakeMove(m), castlingAfter(castling, m))] < 2 {
+			allowed = append(allowed, m)
+		}
+	}
+	if len(allowed) == 0 {
+		return moves
+	}
+	return allowed
+}
+
+// SANMoves returns moves of the game in standard algebraic notation.
+func SANMoves(g *game.Game) []string {
+	sanMoves := make([]string, 0, len(g.Positions)-1)
+	for i := range g.Positions {
+		if g.Positions[i].LastMove != move.Null {
+			sanMoves = append(sanMoves, g.Positions[i-1].SAN(g.Positions[i].LastMove))
+		}
+	}
+	return sanMoves
+}
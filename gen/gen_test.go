@@ -0,0 +1,28 @@
+package gen
+
+import (
+	"context"
+	"testing"
+)
+
+// BenchmarkGenerateRandomGame measures generating one game, with seeds growing from 0 over iterations.
+func BenchmarkGenerateRandomGame(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i += 1 {
+		if _, err := GenerateRandomGame(context.Background(), int64(i)); err != nil {
+			b.Fatalf("generating game with seed #%d: %v", i, err)
+		}
+	}
+}
+
+// BenchmarkGenerate1000 measures generating games for seeds from 0 to 999 one by one, like a run with -searches 1000 -workers 1.
+func BenchmarkGenerate1000(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i += 1 {
+		for seed := int64(0); seed < 1000; seed += 1 {
+			if _, err := Generate(seed, Options{}); err != nil {
+				b.Fatalf("generating game with seed #%d: %v", seed, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package gen
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "Regenerate golden files from the current library instead of comparing. Use only when the change of generated games is intentional, because stored games won't reproduce.")
+
+// goldenSeeds are seeds of games, whose SAN moves are compared to golden files.
+// If the upstream chess library changes move generation, the games change and games in storage files are not reproducible anymore.
+var goldenSeeds = []int64{0, 1, 2, 7, 42}
+
+// Returns name of the golden file for the seed.
+func goldenFileName(seed int64) string {
+	return filepath.Join("testdata", "golden", fmt.Sprintf("seed_%d.san", seed))
+}
+
+func TestGolden(t *testing.T) {
+	for _, seed := range goldenSeeds {
+		g, err := GenerateRandomGame(context.Background(), seed)
+		if err != nil {
+			t.Fatalf("generating game #%d: %v", seed, err)
+		}
+		got := strings.Join(SANMoves(g), " ") + "\n"
+		name := goldenFileName(seed)
+		if *updateGolden {
+			if err := os.WriteFile(name, []byte(got), 0644); err != nil {
+				t.Fatalf("writing golden file %q: %v", name, err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("reading golden file %q: %v", name, err)
+		}
+		if string(want) != got {
+			t.Errorf("game #%d differs from golden file %q, generation differs from the version of the chess library the golden files were written with\n got: %s want: %s", seed, name, got, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/square"
+)
+
+// GameHash returns a hash of the SAN move list of the game.
+// Games with the same moves have the same hash, regardless of their seeds.
+// Games loaded from storage (without positions) are hashed from their "sanMoves" tag.
+func GameHash(g *game.Game) string {
+	sanMoves := g.Tags["sanMoves"]
+	if len(g.Positions) > 0 {
+		sanMoves = strings.Join(SANMoves(g), " ")
+	}
+	if fen, ok := g.Tags[TagFEN]; ok {
+		sanMoves = fen + "\n" + sanMoves
+	}
+	sum := sha256.Sum256([]byte(sanMoves))
+	return hex.EncodeToString(sum[:])
+}
+
+// Random keys of Zobrist hashing in PositionHash.
+// They are generated by splitmix64 from a fixed seed, so hashes are stable across runs and Go versions.
+var (
+	// Indexed by color (0 White, 1 Black), piece type and square.
+	zobristPieces [2][7][64]uint64
+	zobristBlack  uint64
+	// Indexed by castling right letter in FEN: K, Q, k, q.
+	zobristCastling = map[rune]uint64{}
+	// Indexed by en passant square.
+	zobristEnPassant [64]uint64
+)
+
+func init() {
+	state := uint64(0x636865737367656e)
+	next := func() uint64 {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+	for c := range zobristPieces {
+		for t := range zobristPieces[c] {
+			for sq := range zobristPieces[c][t] {
+				zobristPieces[c][t][sq] = next()
+			}
+		}
+	}
+	zobristBlack = next()
+	for _, r := range "KQkq" {
+		zobristCastling[r] = next()
+	}
+	for sq := range zobristEnPassant {
+		zobristEnPassant[sq] = next()
+	}
+}
+
+// PositionHash returns a Zobrist hash of the position, which incorporates piece placement, side to move, castling rights and en passant square.
+// Repeated positions have the same hash, so it can be used for repetition detection and caching.
+// Hashes are stable, i.e. the same position has the same hash in every run.
+func PositionHash(pos *position.Position) uint64 {
+	return positionHash(pos, castlingRights(pos))
+}
+
+// positionHash returns PositionHash of the position with castling rights given as in FEN (e.g. "KQq" or "-").
+func positionHash(pos *position.Position, castling string) uint64 {
+	h := uint64(0)
+	for i := 0; i < 64; i++ {
+		p := pos.OnSquare(square.Square(i))
+		if p.Type == piece.None {
+			continue
+		}
+		c := 0
+		if p.Color == piece.Black {
+			c = 1
+		}
+		h ^= zobristPieces[c][p.Type][i]
+	}
+	if pos.ActiveColor == piece.Black {
+		h ^= zobristBlack
+	}
+	for _, r := range castling {
+		h ^= zobristCastling[r]
+	}
+	if pos.EnPassant != square.NoSquare && int(pos.EnPassant) < 64 {
+		h ^= zobristEnPassant[pos.EnPassant]
+	}
+	return h
+}
+
+// castlingRights returns castling rights of the position as in FEN.
+// Castling rights are not exposed by position, so they are taken from FEN.
+func castlingRights(pos *position.Position) string {
+	s, err := fen.Encode(pos)
+	if err != nil {
+		return ""
+	}
+	if fields := strings.Fields(s); len(fields) > 2 {
+		return fields[2]
+	}
+	return ""
+}
+
+// Castling rights lost by a move from or to the square, because the king or the rook moves or the rook is captured.
+var castlingLost = map[square.Square]string{
+	square.Parse("e1"): "KQ",
+	square.Parse("h1"): "K",
+	square.Parse("a1"): "Q",
+	square.Parse("e8"): "kq",
+	square.Parse("h8"): "k",
+	square.Parse("a8"): "q",
+}
+
+// castlingAfter returns castling rights after the move in a position with castling rights given as in FEN, without encoding the new position.
+func castlingAfter(castling string, m move.Move) string {
+	lost := castlingLost[m.Source] + castlingLost[m.Destination]
+	if lost == "" {
+		return castling
+	}
+	rights := strings.Builder{}
+	for _, r := range castling {
+		if r != '-' && !strings.ContainsRune(lost, r) {
+			rights.WriteRune(r)
+		}
+	}
+	return rights.String()
+}
@@ -0,0 +1,36 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/andrewbackes/chess/fen"
+)
+
+func TestPositionHashEnPassant(t *testing.T) {
+	withEP, err := fen.Decode("rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutEP, err := fen.Decode("rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if PositionHash(withEP) == PositionHash(withoutEP) {
+		t.Errorf("positions differing only in en passant square have the same hash %x", PositionHash(withEP))
+	}
+}
+
+func TestCastlingAfter(t *testing.T) {
+	// Kings, rooks and captures of rooks change castling rights.
+	pos, err := fen.Decode("r3k2r/8/8/8/8/8/6b1/R3K2R b KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	castling := castlingRights(pos)
+	for m := range pos.LegalMoves() {
+		next := pos.MakeMove(m)
+		if got, want := positionHash(next, castlingAfter(castling, m)), PositionHash(next); got != want {
+			t.Errorf("move %v: hash with derived castling rights %q is %x, want %x with castling rights %q", m, castlingAfter(castling, m), got, want, castlingRights(next))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package gen
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// LengthHistogram counts games by half-move length in buckets of Width half-moves.
+type LengthHistogram struct {
+	Width   int
+	lengths []int
+}
+
+// NewLengthHistogram returns an empty histogram with buckets of width half-moves.
+// Width lower than 1 is treated as 1.
+func NewLengthHistogram(width int) *LengthHistogram {
+	if width < 1 {
+		width = 1
+	}
+	return &LengthHistogram{Width: width}
+}
+
+// Add counts a game with halfMoves half-moves.
+func (h *LengthHistogram) Add(halfMoves int) {
+	h.lengths = append(h.lengths, halfMoves)
+}
+
+// Count returns the number of counted games.
+func (h *LengthHistogram) Count() int {
+	return len(h.lengths)
+}
+
+// Min, max, mean and median of counted lengths. All are zero for an empty histogram.
+func (h *LengthHistogram) summary() (min, max int, mean, median float64) {
+	if len(h.lengths) == 0 {
+		return 0, 0, 0, 0
+	}
+	sorted := append([]int(nil), h.lengths...)
+	sort.Ints(sorted)
+	sum := 0
+	for _, l := range sorted {
+		sum += l
+	}
+	n := len(sorted)
+	median = float64(sorted[n/2])
+	if n%2 == 0 {
+		median = float64(sorted[n/2-1]+sorted[n/2]) / 2
+	}
+	return sorted[0], sorted[n-1], float64(sum) / float64(n), median
+}
+
+// WriteReport writes summary of counted lengths (min, max, mean, median) and a histogram of buckets to w.
+// Empty buckets between the shortest and the longest game are written too.
+func (h *LengthHistogram) WriteReport(w io.Writer) error {
+	min, max, mean, median := h.summary()
+	if _, err := fmt.Fprintf(w, "Games: %d\nMin: %d\nMax: %d\nMean: %.2f\nMedian: %.1f\n", len(h.lengths), min, max, mean, median); err != nil {
+		return err
+	}
+	if len(h.lengths) == 0 {
+		return nil
+	}
+	counts := map[int]int{}
+	most := 0
+	for _, l := range h.lengths {
+		counts[l/h.Width] += 1
+		if counts[l/h.Width] > most {
+			most = counts[l/h.Width]
+		}
+	}
+	// Longest bar has 50 characters.
+	const barLength = 50
+	for b := min / h.Width; b <= max/h.Width; b += 1 {
+		bar := strings.Repeat("#", (counts[b]*barLength+most-1)/most)
+		if _, err := fmt.Fprintf(w, "%5d-%-5d %7d %s\n", b*h.Width, (b+1)*h.Width-1, counts[b], bar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
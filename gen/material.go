@@ -0,0 +1,116 @@
+package gen
+
+import (
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/square"
+)
+
+// IsInsufficientMaterial reports whether neither side has enough material to checkmate.
+// It covers king against king, king and a minor piece against king, and positions where all pieces besides kings are bishops on squares of the same color.
+func IsInsufficientMaterial(pos *position.Position) bool {
+	knights, bishops := 0, 0
+	bishopSquareColors := map[int]bool{}
+	for i := 0; i < 64; i++ {
+		switch pos.OnSquare(square.Square(i)).Type {
+		case piece.None, piece.King:
+		case piece.Knight:
+			knights += 1
+		case piece.Bishop:
+			bishops += 1
+			file, rank := fileRank(square.Square(i))
+			bishopSquareColors[(file+rank)%2] = true
+		default:
+			return false
+		}
+	}
+	if knights+bishops <= 1 {
+		return true
+	}
+	return knights == 0 && len(bishopSquareColors) == 1
+}
+
+// Values of pieces in centipawns used by MaterialBalance.
+var pieceValues = map[piece.Type]int{
+	piece.Pawn:   100,
+	piece.Knight: 300,
+	piece.Bishop: 300,
+	piece.Rook:   500,
+	piece.Queen:  900,
+}
+
+// MaterialBalance returns material of White minus material of Black in the position, in centipawns.
+// Pawns are worth 100, knights and bishops 300, rooks 500 and queens 900 centipawns.
+func MaterialBalance(pos *position.Position) int {
+	balance := 0
+	for i := 0; i < 64; i++ {
+		p := pos.OnSquare(square.Square(i))
+		if p.Color == piece.White {
+			balance += pieceValues[p.Type]
+		} else {
+			balance -= pieceValues[p.Type]
+		}
+	}
+	return balance
+}
+
+// MaterialBalances returns material balance (see MaterialBalance) of every position of the game, from the starting to the last one.
+func MaterialBalances(g *game.Game) []int {
+	balances := make([]int, 0, len(g.Positions))
+	for _, pos := range g.Positions {
+		balances = append(balances, MaterialBalance(pos))
+	}
+	return balances
+}
+
+// IsDeadDraw reports whether the position is a dead draw in a conservative subset of dead positions, which is easy to detect:
+// there are only kings and pawns, every file has a white pawn blocked by a black pawn right in front of it,
+// pawns on neighbouring files are one rank apart, so no pawn can capture, every pawn a king can reach is defended by a pawn,
+// and both kings are behind their own pawns. Neither side can then capture nor promote and the game can't be won.
+// Other dead positions (e.g. with bishops or partial pawn walls, which kings can't cross anyway) are not detected.
+func IsDeadDraw(pos *position.Position) bool {
+	// Ranks of white pawns on files a to h, -1 if there is none yet.
+	whiteRanks := [8]int{-1, -1, -1, -1, -1, -1, -1, -1}
+	blackRanks := whiteRanks
+	whiteKing, blackKing := square.NoSquare, square.NoSquare
+	for i := 0; i < 64; i++ {
+		sq := square.Square(i)
+		p := pos.OnSquare(sq)
+		file, rank := fileRank(sq)
+		switch p.Type {
+		case piece.None:
+		case piece.King:
+			if p.Color == piece.White {
+				whiteKing = sq
+			} else {
+				blackKing = sq
+			}
+		case piece.Pawn:
+			ranks := &whiteRanks
+			if p.Color == piece.Black {
+				ranks = &blackRanks
+			}
+			if ranks[file] >= 0 {
+				return false
+			}
+			ranks[file] = rank
+		default:
+			return false
+		}
+	}
+	for f := 0; f < 8; f++ {
+		if whiteRanks[f] < 0 || blackRanks[f] != whiteRanks[f]+1 {
+			return false
+		}
+		if f > 0 && whiteRanks[f]-whiteRanks[f-1] != 1 && whiteRanks[f-1]-whiteRanks[f] != 1 {
+			return false
+		}
+	}
+	if whiteKing == square.NoSquare || blackKing == square.NoSquare {
+		return false
+	}
+	whiteFile, whiteRank := fileRank(whiteKing)
+	blackFile, blackRank := fileRank(blackKing)
+	return whiteRank < whiteRanks[whiteFile] && blackRank > blackRanks[blackFile]
+}
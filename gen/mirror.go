@@ -0,0 +1,78 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// TagMirrored is a game tag set to "true" for games mirrored by MirrorGame.
+const TagMirrored = "Mirrored"
+
+// MirrorGame returns the left-right mirror of the game with positions, with files a and h swapped.
+// The mirrored game starts from the mirrored starting position without castling rights and every move of the game is reflected and played in it.
+// Games with castling can't be mirrored, because castling is not possible with mirrored kings and rooks.
+// An error is returned, if a reflected move is not legal, or the mirrored game differs from the game in length or status.
+func MirrorGame(g *game.Game) (*game.Game, error) {
+	if len(g.Positions) == 0 {
+		return nil, fmt.Errorf("gen: game has no positions to mirror")
+	}
+	startFEN, err := fen.Encode(g.Positions[0])
+	if err != nil {
+		return nil, fmt.Errorf("gen: encoding starting position: %v", err)
+	}
+	mirroredFEN, err := mirrorFEN(startFEN)
+	if err != nil {
+		return nil, err
+	}
+	mg, err := newGame(mirroredFEN)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(g.Positions); i++ {
+		m := g.Positions[i].LastMove
+		mm := move.Move{Source: m.Source ^ 7, Destination: m.Destination ^ 7, Promote: m.Promote}
+		if _, ok := mg.LegalMoves()[mm]; !ok {
+			return nil, fmt.Errorf("gen: mirror of move %d %q is not legal", i, g.Positions[i-1].SAN(m))
+		}
+		if _, err := mg.MakeMove(mm); err != nil {
+			return nil, fmt.Errorf("gen: playing mirror of move %d: %v", i, err)
+		}
+	}
+	if GameLength(mg) != GameLength(g) || mg.Status() != g.Status() {
+		return nil, fmt.Errorf("gen: mirrored game has %d half-moves and status %v, game has %d half-moves and status %v", GameLength(mg), mg.Status(), GameLength(g), g.Status())
+	}
+	for _, tag := range []string{"#", TagAdjudication, TagTruncated, TagStoppedAtPly, TagCaptures, TagChecks, TagPromotions} {
+		if v, ok := g.Tags[tag]; ok {
+			mg.Tags[tag] = v
+		}
+	}
+	mg.Tags[TagMirrored] = "true"
+	return mg, nil
+}
+
+// Returns the FEN with files of the board and en passant square mirrored and no castling rights.
+func mirrorFEN(s string) (string, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return "", fmt.Errorf("gen: invalid FEN %q", s)
+	}
+	ranks := strings.Split(fields[0], "/")
+	for i, rank := range ranks {
+		// Every character is a piece or a number of empty squares, so reversing characters mirrors the rank.
+		b := []byte(rank)
+		for l, r := 0, len(b)-1; l < r; l, r = l+1, r-1 {
+			b[l], b[r] = b[r], b[l]
+		}
+		ranks[i] = string(b)
+	}
+	fields[0] = strings.Join(ranks, "/")
+	fields[2] = "-"
+	if ep := fields[3]; ep != "-" {
+		fields[3] = string('a'+'h'-ep[0]) + ep[1:]
+	}
+	return strings.Join(fields, " "), nil
+}
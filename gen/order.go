@@ -0,0 +1,48 @@
+package gen
+
+import (
+	"sort"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position/move"
+	"github.com/andrewbackes/chess/square"
+)
+
+// Order of promotion pieces in canonical move ordering, by their letter in coordinate notation (b, n, q, r).
+var promotionOrder = map[piece.Type]int{
+	piece.None:   0,
+	piece.Bishop: 1,
+	piece.Knight: 2,
+	piece.Queen:  3,
+	piece.Rook:   4,
+}
+
+// MoveLess reports whether move a comes before move b in the canonical move ordering.
+// Moves are compared by source square, destination square and promotion piece, where squares are ordered by file and then by rank (a1, a2, ..., h8).
+// The ordering is the same as ordering by coordinate notation ("e2e4", "e7e8q"), but doesn't depend on move.Move.String, so seeds stay reproducible if its formatting changes.
+func MoveLess(a, b move.Move) bool {
+	if a.Source != b.Source {
+		return squareLess(a.Source, b.Source)
+	}
+	if a.Destination != b.Destination {
+		return squareLess(a.Destination, b.Destination)
+	}
+	return promotionOrder[a.Promote] < promotionOrder[b.Promote]
+}
+
+// SortMoves sorts moves in the canonical move ordering (see MoveLess).
+func SortMoves(moves []move.Move) {
+	sort.Slice(moves, func(i, j int) bool {
+		return MoveLess(moves[i], moves[j])
+	})
+}
+
+// Reports whether square a comes before square b, when ordered by file and then by rank.
+func squareLess(a, b square.Square) bool {
+	fileA, rankA := fileRank(a)
+	fileB, rankB := fileRank(b)
+	if fileA != fileB {
+		return fileA < fileB
+	}
+	return rankA < rankB
+}
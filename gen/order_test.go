@@ -0,0 +1,35 @@
+package gen
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/andrewbackes/chess/position/move"
+)
+
+func TestMoveLessMatchesStringOrder(t *testing.T) {
+	for _, startFEN := range []string{
+		"",
+		// Promotions with and without capture for both sides, castling and en passant.
+		"r3k2r/1P4P1/8/3pP3/8/8/1p4p1/R3K2R w KQkq d6 0 1",
+	} {
+		g, err := newGame(startFEN)
+		if err != nil {
+			t.Fatalf("starting game from %q: %v", startFEN, err)
+		}
+		moves := []move.Move{}
+		for m := range g.LegalMoves() {
+			moves = append(moves, m)
+		}
+		SortMoves(moves)
+		want := append([]move.Move{}, moves...)
+		sort.Slice(want, func(i, j int) bool {
+			return want[i].String() < want[j].String()
+		})
+		for i := range moves {
+			if moves[i] != want[i] {
+				t.Errorf("position %q: move %d in canonical order is %v, in order of Move.String it is %v", startFEN, i, moves[i], want[i])
+			}
+		}
+	}
+}
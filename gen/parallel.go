@@ -0,0 +1,135 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// GameResult is a game generated for the seed, or an error if the generation failed.
+// Duration is the wall-clock time of generating the game.
+type GameResult struct {
+	Seed     int64
+	Game     *game.Game
+	Err      error
+	Duration time.Duration
+}
+
+// GenerateParallel generates games for seeds with opts in workers goroutines and calls fn with the results in the order of seeds.
+// Results are delivered in the order of seeds regardless of which worker finishes first, so the output is deterministic
+// and the same as generating games one by one with Generate, for any number of workers.
+// Only opts.BailBelow may be called at different times, so which games are abandoned depends on timing.
+// If fn returns an error, generation stops and the error is returned.
+// If ctx is cancelled, games being generated stop promptly, no more results are delivered and ctx.Err() is returned.
+// The opts.OnGame hook is called in the order of seeds too, right before fn.
+func GenerateParallel(ctx context.Context, seeds []int64, workers int, opts Options, fn func(GameResult) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	onGame := opts.OnGame
+	opts.OnGame = nil
+	type indexedResult struct {
+		index int
+		GameResult
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	// Cancels games being generated, when generation stops.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Limits the number of generated games waiting for delivery, so memory doesn't grow when one seed takes long.
+	window := make(chan struct{}, 4*workers)
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range seeds {
+			select {
+			case window <- struct{}{}:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan indexedResult)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w += 1 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				start := time.Now()
+				g, err := GenerateContext(ctx, seeds[i], opts)
+				select {
+				case results <- indexedResult{i, GameResult{seeds[i], g, err, time.Since(start)}}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := map[int]GameResult{}
+	next := 0
+	for r := range results {
+		pending[r.index] = r.GameResult
+		for {
+			pr, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next += 1
+			<-window
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if pr.Err == nil && onGame != nil {
+				onGame(pr.Seed, pr.Game)
+			}
+			if err := fn(pr); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// GenerateN generates games for seeds with opts in memory and returns them in the order of seeds.
+// It never touches disk. Games are generated in parallel using all available CPUs.
+// If generation of any game fails, the first error in the order of seeds is returned.
+// It is meant for tests and library use. The command of this module uses GenerateParallel directly,
+// because it stores games as they are delivered, counts bailed and failed seeds and stops on interrupt.
+func GenerateN(seeds []int64, opts Options) ([]*game.Game, error) {
+	games := make([]*game.Game, 0, len(seeds))
+	err := GenerateParallel(context.Background(), seeds, runtime.GOMAXPROCS(0), opts, func(r GameResult) error {
+		if r.Err != nil {
+			return fmt.Errorf("gen: generating game with seed #%d: %w", r.Seed, r.Err)
+		}
+		games = append(games, r.Game)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return games, nil
+}
@@ -0,0 +1,62 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateParallelCancel(t *testing.T) {
+	seeds := make([]int64, 1000)
+	for i := range seeds {
+		seeds[i] = int64(i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	delivered := 0
+	err := GenerateParallel(ctx, seeds, 4, Options{}, func(r GameResult) error {
+		delivered += 1
+		if r.Seed == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("generation cancelled after seed #2 returned %v, want %v", err, context.Canceled)
+	}
+	if delivered != 3 {
+		t.Errorf("generation cancelled after seed #2 delivered %d results, want 3", delivered)
+	}
+	err = GenerateParallel(ctx, seeds, 4, Options{}, func(r GameResult) error {
+		t.Errorf("cancelled generation delivered result for seed #%d", r.Seed)
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("generation with cancelled context returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestGenerateN(t *testing.T) {
+	seeds := []int64{7, 0, 42}
+	opts := Options{MaxHalfMoves: 60}
+	games, err := GenerateN(seeds, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != len(seeds) {
+		t.Fatalf("generated %d games, want %d", len(games), len(seeds))
+	}
+	for i, seed := range seeds {
+		want, err := Generate(seed, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := strings.Join(SANMoves(games[i]), " "), strings.Join(SANMoves(want), " "); got != want {
+			t.Errorf("game %d for seed #%d has moves %q, want %q", i, seed, got, want)
+		}
+	}
+	if _, err := GenerateN(seeds, Options{FEN: "invalid"}); err == nil {
+		t.Errorf("generating games from invalid FEN: expected error")
+	}
+}
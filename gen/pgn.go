@@ -0,0 +1,171 @@
+package gen
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+)
+
+// Maximal length of a movetext line in PGN export format.
+const pgnLineLength = 79
+
+// PGNResult returns the PGN game termination marker for the game status.
+// Checkmates map to the winning side, stalemate and all other draws (insufficient material, fifty-move rule, threefold repetition) map to "1/2-1/2".
+// Games still in progress map to "*".
+func PGNResult(gs game.GameStatus) string {
+	switch {
+	case gs&game.WhiteWon != 0:
+		return "1-0"
+	case gs&game.BlackWon != 0:
+		return "0-1"
+	case gs&game.Draw != 0:
+		return "1/2-1/2"
+	}
+	return "*"
+}
+
+// PGNOptions configures writing of PGN. The zero value writes PGN export format.
+type PGNOptions struct {
+	// Clock writes a "{[%clk 0:00:00]}" comment after every move for GUIs requiring clock data.
+	// Games are generated instantly, so the clock is synthetic and always zero.
+	Clock bool
+	// EmbedFENs writes a comment with FEN of the resulting position after every move, e.g. for step by step verification in a GUI.
+	// It enlarges the output many times.
+	EmbedFENs bool
+}
+
+// WritePGN writes the game to w in PGN export format.
+// The seven tag roster is filled from game tags, if present, and the Result tag is computed from GameStatus(g).
+// Games not starting from the initial position get SetUp and FEN tags, other games ECO and Opening tags of their opening (see ClassifyOpening).
+// Seed of the game is written in a Seed tag and TagTarget, if present, in a Target tag.
+func WritePGN(w io.Writer, g *game.Game) error {
+	return PGNOptions{}.WritePGN(w, g)
+}
+
+// WritePGN is like the WritePGN function, but adds comments configured by options.
+func (o PGNOptions) WritePGN(w io.Writer, g *game.Game) error {
+	if len(g.Positions) == 0 {
+		return errors.New("gen: can't write PGN for game without positions")
+	}
+	result := PGNResult(GameStatus(g))
+	bw := bufio.NewWriter(w)
+
+	roster := []struct{ name, value string }{
+		{"Event", "Random game"},
+		{"Site", "?"},
+		{"Date", "????.??.??"},
+		{"Round", "?"},
+		{"White", "?"},
+		{"Black", "?"},
+		{"Result", result},
+	}
+	for _, tag := range roster {
+		value := tag.value
+		if v, ok := g.Tags[tag.name]; ok && tag.name != "Result" {
+			value = v
+		}
+		writePGNTag(bw, tag.name, value)
+	}
+	if startFEN, ok := g.Tags[TagFEN]; ok {
+		writePGNTag(bw, "SetUp", "1")
+		writePGNTag(bw, "FEN", startFEN)
+	}
+	if eco, opening := ClassifyOpening(g); eco != "" {
+		writePGNTag(bw, "ECO", eco)
+		writePGNTag(bw, "Opening", opening)
+	}
+	if seed, ok := g.Tags["#"]; ok {
+		writePGNTag(bw, "Seed", seed)
+	}
+	if target, ok := g.Tags[TagTarget]; ok {
+		writePGNTag(bw, TagTarget, target)
+	}
+	bw.WriteString("\n")
+
+	lineLength := 0
+	writeToken := func(token string) {
+		if lineLength > 0 && lineLength+1+len(token) > pgnLineLength {
+			bw.WriteString("\n")
+			lineLength = 0
+		}
+		if lineLength > 0 {
+			bw.WriteString(" ")
+			lineLength += 1
+		}
+		bw.WriteString(token)
+		lineLength += len(token)
+	}
+	for i := 1; i < len(g.Positions); i++ {
+		prev := g.Positions[i-1]
+		// White's move gets its move number, Black's move only if it is the first move or follows a comment after White's move.
+		if prev.ActiveColor == piece.White {
+			writeToken(fmt.Sprintf("%d.", prev.MoveNumber))
+		} else if i == 1 || o.Clock || o.EmbedFENs {
+			writeToken(fmt.Sprintf("%d...", prev.MoveNumber))
+		}
+		writeToken(prev.SAN(g.Positions[i].LastMove))
+		if o.Clock {
+			writeToken("{[%clk 0:00:00]}")
+		}
+		if o.EmbedFENs {
+			s, err := fen.Encode(g.Positions[i])
+			if err != nil {
+				return fmt.Errorf("gen: encoding position after half-move %d: %v", i, err)
+			}
+			// FEN comments are wrapped at spaces, so lines are not longer than pgnLineLength.
+			for _, word := range strings.Fields("{" + s + "}") {
+				writeToken(word)
+			}
+		}
+	}
+	writeToken(result)
+	bw.WriteString("\n")
+
+	return bw.Flush()
+}
+
+// TagTarget is a game tag holding the target length, for which the game was selected. It is written to PGN as a Target tag.
+const TagTarget = "Target"
+
+// WritePGNDatabase writes games to w as a PGN database, which chess GUIs can open, with an empty line between games.
+// Games without a Round tag get a unique Round tag with their 1-based index in games. Games are not modified.
+func WritePGNDatabase(w io.Writer, games []*game.Game) error {
+	return PGNOptions{}.WritePGNDatabase(w, games)
+}
+
+// WritePGNDatabase is like the WritePGNDatabase function, but games are written with options.
+func (o PGNOptions) WritePGNDatabase(w io.Writer, games []*game.Game) error {
+	for i, g := range games {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if _, ok := g.Tags["Round"]; !ok {
+			tags := make(map[string]string, len(g.Tags)+1)
+			for k, v := range g.Tags {
+				tags[k] = v
+			}
+			tags["Round"] = fmt.Sprint(i + 1)
+			rg := *g
+			rg.Tags = tags
+			g = &rg
+		}
+		if err := o.WritePGN(w, g); err != nil {
+			return fmt.Errorf("gen: writing game %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func writePGNTag(w *bufio.Writer, name, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	fmt.Fprintf(w, "[%s \"%s\"]\n", name, value)
+}
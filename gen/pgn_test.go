@@ -0,0 +1,189 @@
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+)
+
+// pgnGame is a game read by readPGNDatabase.
+type pgnGame struct {
+	tags  map[string]string
+	moves []string
+}
+
+var (
+	pgnTagRegexp        = regexp.MustCompile(`^\[(\w+) "((?:[^"\\]|\\.)*)"\]$`)
+	pgnMoveNumberRegexp = regexp.MustCompile(`^\d+\.(\.\.)?$`)
+	pgnCommentRegexp    = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+// readPGNDatabase reads games from PGN database in export format, with tag pairs and movetext of every game.
+// Comments, move numbers and result are dropped from moves, the result has to match the Result tag.
+func readPGNDatabase(r io.Reader) ([]pgnGame, error) {
+	games := []pgnGame{}
+	var g *pgnGame
+	movetext := ""
+	finish := func() error {
+		if g == nil {
+			return nil
+		}
+		tokens := strings.Fields(pgnCommentRegexp.ReplaceAllString(movetext, " "))
+		if len(tokens) == 0 || tokens[len(tokens)-1] != g.tags["Result"] {
+			return fmt.Errorf("game %d: movetext doesn't end with result %q", len(games)+1, g.tags["Result"])
+		}
+		for _, token := range tokens[:len(tokens)-1] {
+			if !pgnMoveNumberRegexp.MatchString(token) {
+				g.moves = append(g.moves, token)
+			}
+		}
+		games = append(games, *g)
+		g, movetext = nil, ""
+		return nil
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := pgnTagRegexp.FindStringSubmatch(line); m != nil {
+			if movetext != "" {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+			}
+			if g == nil {
+				g = &pgnGame{tags: map[string]string{}}
+			}
+			value := strings.ReplaceAll(strings.ReplaceAll(m[2], `\"`, `"`), `\\`, `\`)
+			g.tags[m[1]] = value
+			continue
+		}
+		if g == nil && strings.TrimSpace(line) != "" {
+			return nil, fmt.Errorf("game %d: movetext without tags", len(games)+1)
+		}
+		movetext += " " + line
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+func TestWritePGNDatabaseRoundTrip(t *testing.T) {
+	games := []*game.Game{}
+	for _, opts := range []Options{{}, {MaxHalfMoves: 40}, {FEN: "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1"}} {
+		for seed := int64(0); seed < 3; seed += 1 {
+			g, err := Generate(seed, opts)
+			if err != nil {
+				t.Fatalf("generating game #%d: %v", seed, err)
+			}
+			games = append(games, g)
+		}
+	}
+	for _, o := range []PGNOptions{{}, {Clock: true}, {EmbedFENs: true}} {
+		buf := bytes.Buffer{}
+		if err := o.WritePGNDatabase(&buf, games); err != nil {
+			t.Fatalf("writing PGN database with %+v: %v", o, err)
+		}
+		read, err := readPGNDatabase(&buf)
+		if err != nil {
+			t.Fatalf("reading PGN database written with %+v: %v", o, err)
+		}
+		if len(read) != len(games) {
+			t.Fatalf("read %d games from PGN database written with %+v, want %d", len(read), o, len(games))
+		}
+		for i, g := range games {
+			rg := read[i]
+			if got, want := strings.Join(rg.moves, " "), strings.Join(SANMoves(g), " "); got != want {
+				t.Errorf("game %d written with %+v has moves %q, want %q", i+1, o, got, want)
+			}
+			if got, want := rg.tags["Seed"], g.Tags["#"]; got != want {
+				t.Errorf("game %d written with %+v has Seed tag %q, want %q", i+1, o, got, want)
+			}
+			if got, want := rg.tags["Round"], fmt.Sprint(i+1); got != want {
+				t.Errorf("game %d written with %+v has Round tag %q, want %q", i+1, o, got, want)
+			}
+			if got, want := rg.tags["Result"], PGNResult(GameStatus(g)); got != want {
+				t.Errorf("game %d written with %+v has Result tag %q, want %q", i+1, o, got, want)
+			}
+			if got, want := rg.tags["FEN"], g.Tags[TagFEN]; got != want {
+				t.Errorf("game %d written with %+v has FEN tag %q, want %q", i+1, o, got, want)
+			}
+			replayed, err := ReplaySANFrom(rg.tags["FEN"], rg.moves)
+			if err != nil {
+				t.Errorf("replaying game %d written with %+v: %v", i+1, o, err)
+				continue
+			}
+			if got, want := GameStatus(replayed), GameStatus(g); got != want {
+				t.Errorf("replayed game %d written with %+v has status %v, want %v", i+1, o, got, want)
+			}
+		}
+	}
+}
+
+// pgnMovetext writes the game as PGN with options and returns its movetext, which follows the empty line after tag pairs.
+func pgnMovetext(t *testing.T, o PGNOptions, g *game.Game) string {
+	t.Helper()
+	buf := bytes.Buffer{}
+	if err := o.WritePGN(&buf, g); err != nil {
+		t.Fatalf("writing PGN with %+v: %v", o, err)
+	}
+	_, movetext, ok := strings.Cut(buf.String(), "\n\n")
+	if !ok {
+		t.Fatalf("PGN written with %+v has no movetext: %q", o, buf.String())
+	}
+	return movetext
+}
+
+func TestWritePGNMoveNumberAfterComment(t *testing.T) {
+	g, err := Generate(0, Options{MaxHalfMoves: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range []PGNOptions{{Clock: true}, {EmbedFENs: true}, {Clock: true, EmbedFENs: true}} {
+		tokens := strings.Fields(pgnCommentRegexp.ReplaceAllString(pgnMovetext(t, o, g), " {} "))
+		for i := 1; i < len(tokens)-1; i += 1 {
+			if next := tokens[i+1]; tokens[i] == "{}" && next != "{}" && !pgnMoveNumberRegexp.MatchString(next) && next != PGNResult(GameStatus(g)) {
+				t.Errorf("PGN written with %+v has move %s after comment without move number", o, next)
+			}
+		}
+	}
+}
+
+func TestWritePGNWrapsFENComments(t *testing.T) {
+	// FEN comments of positions with many pieces on fragmented ranks are longer than a line.
+	const startFEN = "r1b1k1nr/p1p1p1p1/1p1p1p1p/1n1b1q2/1N1B1Q2/P1P1P1P1/1P1P1P1P/R1B1K1NR w KQkq - 10 20"
+	g, err := Generate(0, Options{FEN: startFEN, MaxHalfMoves: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := PGNOptions{Clock: true, EmbedFENs: true}
+	movetext := pgnMovetext(t, o, g)
+	for i, line := range strings.Split(strings.TrimSuffix(movetext, "\n"), "\n") {
+		if len(line) > pgnLineLength {
+			t.Errorf("movetext line %d written with %+v has %d characters, more than %d: %q", i+1, o, len(line), pgnLineLength, line)
+		}
+	}
+	comments := pgnCommentRegexp.FindAllString(strings.ReplaceAll(movetext, "\n", " "), -1)
+	if len(comments) != 2*len(SANMoves(g)) {
+		t.Fatalf("movetext written with %+v has %d comments, want %d", o, len(comments), 2*len(SANMoves(g)))
+	}
+	for i := 1; i < len(g.Positions); i += 1 {
+		want, err := fen.Encode(g.Positions[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := comments[2*i-1]; got != "{"+want+"}" {
+			t.Errorf("FEN comment after half-move %d written with %+v is %q, want %q", i, o, got, "{"+want+"}")
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package gen
+
+import (
+	"math/rand"
+
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// Picker picks one of the legal moves in the position.
+// Moves are never empty and are always sorted in the same order, so picking only by rnd keeps games reproducible.
+type Picker func(rnd *rand.Rand, pos *position.Position, moves []move.Move) move.Move
+
+// UniformPicker picks every move with the same probability.
+// It is the default picker and games generated by it are the ones stored in storage files.
+func UniformPicker(rnd *rand.Rand, pos *position.Position, moves []move.Move) move.Move {
+	return moves[rnd.Intn(len(moves))]
+}
+
+// FirstSANPicker picks the move with alphabetically first SAN, so it plays deterministically without using rnd.
+func FirstSANPicker(rnd *rand.Rand, pos *position.Position, moves []move.Move) move.Move {
+	first, firstSAN := moves[0], pos.SAN(moves[0])
+	for _, m := range moves[1:] {
+		if san := pos.SAN(m); san < firstSAN {
+			first, firstSAN = m, san
+		}
+	}
+	return first
+}
+
+// WeightedPicker returns a picker, which picks moves with probability proportional to weight of the move.
+// Moves with non-positive weight are never picked, unless all moves have non-positive weight, then the pick is uniform.
+func WeightedPicker(weight func(pos *position.Position, m move.Move) float64) Picker {
+	return func(rnd *rand.Rand, pos *position.Position, moves []move.Move) move.Move {
+		weights := make([]float64, len(moves))
+		total := 0.0
+		for i, m := range moves {
+			if w := weight(pos, m); w > 0 {
+				weights[i] = w
+				total += w
+			}
+		}
+		if total == 0 {
+			return UniformPicker(rnd, pos, moves)
+		}
+		r := rnd.Float64() * total
+		for i, w := range weights {
+			if r < w {
+				return moves[i]
+			}
+			r -= w
+		}
+		// Floating point rounding may leave r slightly above zero, pick the last move with weight.
+		for i := len(moves) - 1; i >= 0; i-- {
+			if weights[i] > 0 {
+				return moves[i]
+			}
+		}
+		return moves[len(moves)-1]
+	}
+}
+
+// CapturePicker returns a picker, which picks captures weight times more likely than quiet moves.
+func CapturePicker(weight float64) Picker {
+	return WeightedPicker(func(pos *position.Position, m move.Move) float64 {
+		if IsCapture(pos, m) {
+			return weight
+		}
+		return 1
+	})
+}
+
+// CentralBiasPicker returns a picker, which picks moves to the center (d4, e4, d5, e5) weight times more likely than other moves
+// and moves to the rest of extended center (c3 to f6) (1+weight)/2 times more likely. Weight 1 picks uniformly.
+// To stack the bias with others, combine CentralWeight with other weights using CombineWeights in a WeightedPicker.
+func CentralBiasPicker(weight float64) Picker {
+	return WeightedPicker(CentralWeight(weight))
+}
+
+// CentralWeight returns move weight used by CentralBiasPicker.
+func CentralWeight(weight float64) func(pos *position.Position, m move.Move) float64 {
+	return func(pos *position.Position, m move.Move) float64 {
+		file, rank := fileRank(m.Destination)
+		switch {
+		case file >= 3 && file <= 4 && rank >= 3 && rank <= 4:
+			return weight
+		case file >= 2 && file <= 5 && rank >= 2 && rank <= 5:
+			return (1 + weight) / 2
+		}
+		return 1
+	}
+}
+
+// CombineWeights returns move weight, which is the product of all weights.
+// Biases multiply, e.g. a central capture combined from CentralWeight(2) and a capture weight 3 has weight 6.
+// Weights should not be negative, a zero weight of any of them means the move is never picked (see WeightedPicker).
+func CombineWeights(weights ...func(pos *position.Position, m move.Move) float64) func(pos *position.Position, m move.Move) float64 {
+	return func(pos *position.Position, m move.Move) float64 {
+		w := 1.0
+		for _, weight := range weights {
+			w *= weight(pos, m)
+		}
+		return w
+	}
+}
+
+// MoveFilter returns moves allowed in the position, keeping their order. It should not return empty moves, if moves are not empty.
+type MoveFilter func(pos *position.Position, moves []move.Move) []move.Move
+
+// FilteredPicker returns a picker, which removes moves by filters in the given order and picks one of the remaining moves by picker.
+// It chains move filters with a picker, which can be a biased one, e.g. FilteredPicker(CentralBiasPicker(2), NoEarlyQueen(10)).
+// If picker is nil, UniformPicker is used.
+func FilteredPicker(picker Picker, filters ...MoveFilter) Picker {
+	if picker == nil {
+		picker = UniformPicker
+	}
+	return func(rnd *rand.Rand, pos *position.Position, moves []move.Move) move.Move {
+		for _, filter := range filters {
+			moves = filter(pos, moves)
+		}
+		return picker(rnd, pos, moves)
+	}
+}
+
+// NoEarlyQueen returns a move filter, which removes queen moves in positions before untilPly half-moves from the start of the game (counted by move number),
+// unless only queen moves are legal.
+func NoEarlyQueen(untilPly int) MoveFilter {
+	return func(pos *position.Position, moves []move.Move) []move.Move {
+		ply := (pos.MoveNumber - 1) * 2
+		if pos.ActiveColor == piece.Black {
+			ply += 1
+		}
+		if ply >= untilPly {
+			return moves
+		}
+		allowed := make([]move.Move, 0, len(moves))
+		for _, m := range moves {
+			if pos.OnSquare(m.Source).Type != piece.Queen {
+				allowed = append(allowed, m)
+			}
+		}
+		if len(allowed) == 0 {
+			return moves
+		}
+		return allowed
+	}
+}
+
+// IsCapture reports whether the move captures a piece in the position, including en passant captures.
+func IsCapture(pos *position.Position, m move.Move) bool {
+	if pos.OnSquare(m.Destination).Type != piece.None {
+		return true
+	}
+	return m.Destination == pos.EnPassant && pos.OnSquare(m.Source).Type == piece.Pawn
+}
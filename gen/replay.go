@@ -0,0 +1,129 @@
+package gen
+
+import (
+	"fmt"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// ReplayError is returned when a SAN move can't be replayed.
+// Index is the zero based index of the failing move.
+type ReplayError struct {
+	Index int
+	SAN   string
+	Err   error
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("move %d %q: %v", e.Index+1, e.SAN, e.Err)
+}
+
+func (e *ReplayError) Unwrap() error {
+	return e.Err
+}
+
+// ReplaySAN plays SAN moves from the initial position and returns the resulting game.
+// If a move can't be parsed or applied, or the game ends before the last move, a *ReplayError is returned.
+func ReplaySAN(sanMoves []string) (*game.Game, error) {
+	return ReplaySANFrom("", sanMoves)
+}
+
+// ReplaySANFrom plays SAN moves from the position given by startFEN, or from the initial position if startFEN is empty, like ReplaySAN.
+// Games starting from startFEN have it in TagFEN.
+func ReplaySANFrom(startFEN string, sanMoves []string) (*game.Game, error) {
+	g, err := newGame(startFEN)
+	if err != nil {
+		return nil, err
+	}
+	for i, san := range sanMoves {
+		if gs := g.Status(); gs != game.InProgress {
+			return nil, &ReplayError{i, san, fmt.Errorf("game already ended with %v", gs)}
+		}
+		m, err := findSANMove(g, san)
+		if err != nil {
+			return nil, &ReplayError{i, san, err}
+		}
+		if _, err := g.MakeMove(m); err != nil {
+			return nil, &ReplayError{i, san, err}
+		}
+	}
+	return g, nil
+}
+
+// ParseSANLine replays SAN moves from the initial position and returns the resolved moves in order, e.g. to play them again with an Engine.
+// It is the inverse of SANMoves. If a move can't be parsed or applied, a *ReplayError with index and SAN of the failing move is returned.
+func ParseSANLine(sans []string) ([]move.Move, error) {
+	g, err := ReplaySAN(sans)
+	if err != nil {
+		return nil, err
+	}
+	moves := make([]move.Move, 0, len(sans))
+	for _, pos := range g.Positions[1:] {
+		moves = append(moves, pos.LastMove)
+	}
+	return moves, nil
+}
+
+// Rehydrate reconstructs a game with all positions from SAN moves played from the initial position, e.g. from a game loaded from storage.
+// Unlike games loaded from storage, the returned game has positions, so its status, FEN and stats can be computed.
+// The number of captures and checks is stored in TagCaptures and TagChecks tags.
+func Rehydrate(sanMoves []string) (*game.Game, error) {
+	return RehydrateFrom("", sanMoves)
+}
+
+// RehydrateFrom reconstructs a game from SAN moves played from the position given by startFEN, like Rehydrate.
+// If startFEN is empty, moves are played from the initial position.
+func RehydrateFrom(startFEN string, sanMoves []string) (*game.Game, error) {
+	g, err := ReplaySANFrom(startFEN, sanMoves)
+	if err != nil {
+		return nil, err
+	}
+	stats := Summarize(g)
+	g.Tags[TagCaptures] = fmt.Sprint(stats.Captures)
+	g.Tags[TagChecks] = fmt.Sprint(stats.Checks)
+	return g, nil
+}
+
+// VerifyReplay replays SAN moves of the game in a fresh game from the same starting position and checks that every position, including the final one, has the same FEN as in the original game.
+// On mismatch, a *ReplayError with index of the first move leading to a different position is returned.
+func VerifyReplay(g *game.Game) error {
+	rg, err := newGame(g.Tags[TagFEN])
+	if err != nil {
+		return err
+	}
+	for i, san := range SANMoves(g) {
+		m, err := findSANMove(rg, san)
+		if err != nil {
+			return &ReplayError{i, san, err}
+		}
+		if _, err := rg.MakeMove(m); err != nil {
+			return &ReplayError{i, san, err}
+		}
+		got, err := fen.Encode(rg.Positions[len(rg.Positions)-1])
+		if err != nil {
+			return &ReplayError{i, san, err}
+		}
+		want, err := fen.Encode(g.Positions[i+1])
+		if err != nil {
+			return &ReplayError{i, san, err}
+		}
+		if got != want {
+			return &ReplayError{i, san, fmt.Errorf("replayed position %q differs from original %q", got, want)}
+		}
+	}
+	return nil
+}
+
+// Returns the legal move in the current position of the game, which has the SAN representation.
+// Matching against SAN of legal moves makes it an exact inverse of SANMoves.
+func findSANMove(g *game.Game, san string) (move.Move, error) {
+	pos := g.Positions[len(g.Positions)-1]
+	for m := range g.LegalMoves() {
+		if pos.SAN(m) == san {
+			return m, nil
+		}
+	}
+	return move.Null, fmt.Errorf("no legal move with SAN %q", san)
+}
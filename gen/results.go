@@ -0,0 +1,58 @@
+package gen
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/andrewbackes/chess/game"
+)
+
+// Result is a game selected for a target length.
+type Result struct {
+	Seed      int64    `json:"seed"`
+	HalfMoves int      `json:"halfMoves"`
+	Target    int      `json:"target"`
+	Result    string   `json:"result"`
+	SANMoves  []string `json:"sanMoves"`
+	// MaterialBalance holds material balance of every position of the game, if requested (see MaterialBalances).
+	MaterialBalance []int `json:"materialBalance,omitempty"`
+	// ECO and Opening are code and name of the opening of the game (see ClassifyOpening), empty if unknown.
+	ECO     string `json:"eco,omitempty"`
+	Opening string `json:"opening,omitempty"`
+	// Mirrored is true for mirrors of selected games (see MirrorGame).
+	Mirrored bool `json:"mirrored,omitempty"`
+	// Game is the selected game with positions.
+	Game *game.Game `json:"-"`
+}
+
+// NewResult returns the result for the game with positions selected for the target.
+// Result field holds the PGN game termination marker.
+func NewResult(g *game.Game, target int) Result {
+	seed, _ := GameSeed(g)
+	eco, opening := ClassifyOpening(g)
+	return Result{
+		Seed:      seed,
+		HalfMoves: GameLength(g),
+		Target:    target,
+		Result:    PGNResult(GameStatus(g)),
+		SANMoves:  SANMoves(g),
+		ECO:       eco,
+		Opening:   opening,
+		Mirrored:  g.Tags[TagMirrored] == "true",
+		Game:      g,
+	}
+}
+
+// WriteResultsJSON writes results to w as an indented JSON array.
+func WriteResultsJSON(w io.Writer, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+	b, err := json.MarshalIndent(results, "", "\t")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,15 @@
+package gen
+
+import "github.com/andrewbackes/chess/square"
+
+// Squares of the chess library are numbered from h1 (0) to a8 (63) rank by rank, with files going from h to a.
+
+// fileRank returns the file (0 for a to 7 for h) and the rank (0 for the first to 7 for the eighth) of the square.
+func fileRank(sq square.Square) (file, rank int) {
+	return 7 - int(sq)%8, int(sq) / 8
+}
+
+// squareAt returns the square on the file and the rank numbered as in fileRank.
+func squareAt(file, rank int) square.Square {
+	return square.Square(rank*8 + 7 - file)
+}
@@ -0,0 +1,276 @@
+package gen
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// GameStats summarizes a generated game.
+type GameStats struct {
+	HalfMoves    int `json:"halfMoves"`
+	Captures     int `json:"captures"`
+	Checks       int `json:"checks"`
+	Promotions   int `json:"promotions"`
+	WhiteCastles int `json:"whiteCastles"`
+	BlackCastles int `json:"blackCastles"`
+	// WhiteCastled and BlackCastled report whether the side castled, WhiteCastledSide and BlackCastledSide to which side (see CastleSide).
+	WhiteCastled     bool   `json:"whiteCastled"`
+	BlackCastled     bool   `json:"blackCastled"`
+	WhiteCastledSide string `json:"whiteCastledSide"`
+	BlackCastledSide string `json:"blackCastledSide"`
+	// AvgBranching and MaxBranching are the average and the highest number of legal moves in positions, where a move was played (see Branching).
+	AvgBranching float64 `json:"avgBranching"`
+	MaxBranching int     `json:"maxBranching"`
+	// FirstCheckPly is the ply of the first check in the game (see FirstCheckPly), 0 if there is no check.
+	FirstCheckPly int `json:"firstCheckPly"`
+	// MatingMove is the checkmating move in SAN, empty if the game doesn't end by checkmate.
+	MatingMove string `json:"matingMove,omitempty"`
+	// RepeatedPositions is the number of distinct positions occurring at least twice in the game and MaxRepetition the highest number of occurrences of a position.
+	// Positions are compared by PositionHash.
+	RepeatedPositions int `json:"repeatedPositions"`
+	MaxRepetition     int `json:"maxRepetition"`
+	// HalfmoveClock is the halfmove clock in the final position (see HalfmoveClock).
+	HalfmoveClock int `json:"halfmoveClock"`
+	// ECO and Opening are code and name of the opening of the game (see ClassifyOpening), empty if unknown.
+	ECO     string `json:"eco,omitempty"`
+	Opening string `json:"opening,omitempty"`
+	Status  string `json:"status"`
+}
+
+// Summarize computes statistics of the game by comparing consecutive positions.
+// The game has to have positions, games loaded from storage have to be generated or replayed first.
+func Summarize(g *game.Game) GameStats {
+	stats := GameStats{
+		HalfMoves:        GameLength(g),
+		HalfmoveClock:    HalfmoveClock(g),
+		WhiteCastledSide: CastledNone,
+		BlackCastledSide: CastledNone,
+		Status:           GameStatus(g).String(),
+	}
+	for i := 1; i < len(g.Positions); i++ {
+		prev, cur := g.Positions[i-1], g.Positions[i]
+		m := cur.LastMove
+		if IsCapture(prev, m) {
+			stats.Captures += 1
+		}
+		if cur.Check(cur.ActiveColor) {
+			stats.Checks += 1
+		}
+		if m.Promote != piece.None {
+			stats.Promotions += 1
+		}
+		if IsCastle(prev, m) {
+			if prev.ActiveColor == piece.White {
+				stats.WhiteCastles += 1
+				stats.WhiteCastled, stats.WhiteCastledSide = true, CastleSide(m)
+			} else {
+				stats.BlackCastles += 1
+				stats.BlackCastled, stats.BlackCastledSide = true, CastleSide(m)
+			}
+		}
+	}
+	if total, max := Branching(g); stats.HalfMoves > 0 {
+		stats.AvgBranching = float64(total) / float64(stats.HalfMoves)
+		stats.MaxBranching = max
+	}
+	stats.RepeatedPositions, stats.MaxRepetition = Repetitions(g)
+	stats.ECO, stats.Opening = ClassifyOpening(g)
+	stats.FirstCheckPly, _ = FirstCheckPly(g)
+	if m, ok := MatingMove(g); ok {
+		last := len(g.Positions) - 1
+		stats.MatingMove = g.Positions[last-1].SAN(m)
+	}
+	return stats
+}
+
+// Branching returns the total and the highest number of legal moves in positions of the game, where a move was played, i.e. all but the last position.
+// They are taken from TagLegalMoves and TagMaxLegalMoves tags, set when the moves were generated, if present.
+// Otherwise legal moves are computed for positions of the game.
+func Branching(g *game.Game) (total, max int) {
+	t, errTotal := strconv.Atoi(g.Tags[TagLegalMoves])
+	m, errMax := strconv.Atoi(g.Tags[TagMaxLegalMoves])
+	if errTotal == nil && errMax == nil {
+		return t, m
+	}
+	for i := 0; i+1 < len(g.Positions); i++ {
+		n := len(g.Positions[i].LegalMoves())
+		total += n
+		if n > max {
+			max = n
+		}
+	}
+	return total, max
+}
+
+// Repetitions returns the number of distinct positions occurring at least twice in the game and the highest number of occurrences of a position,
+// with positions compared by PositionHash. The game has to have positions.
+func Repetitions(g *game.Game) (repeated, max int) {
+	counts := make(map[uint64]int, len(g.Positions))
+	for _, pos := range g.Positions {
+		h := PositionHash(pos)
+		counts[h] += 1
+		if counts[h] == 2 {
+			repeated += 1
+		}
+		if counts[h] > max {
+			max = counts[h]
+		}
+	}
+	return repeated, max
+}
+
+// FirstCheckPly returns the ply of the first move giving check, i.e. the number of half-moves played when the side to move got into check.
+// It returns false if no move of the game gives check. The game has to have positions.
+func FirstCheckPly(g *game.Game) (int, bool) {
+	for i := 1; i < len(g.Positions); i++ {
+		if pos := g.Positions[i]; pos.Check(pos.ActiveColor) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// MatingMove returns the last move of the game, if it delivered checkmate, i.e. the side to move is in check and has no legal move.
+// It returns false if the game doesn't end by checkmate. The game has to have positions.
+func MatingMove(g *game.Game) (move.Move, bool) {
+	if len(g.Positions) < 2 {
+		return move.Null, false
+	}
+	last := g.Positions[len(g.Positions)-1]
+	if !last.Check(last.ActiveColor) || len(last.LegalMoves()) > 0 {
+		return move.Null, false
+	}
+	return last.LastMove, true
+}
+
+// IsCastle reports whether the move is a castling in the position, i.e. king moves two files.
+func IsCastle(pos *position.Position, m move.Move) bool {
+	if pos.OnSquare(m.Source).Type != piece.King {
+		return false
+	}
+	d := int(m.Source) - int(m.Destination)
+	return d == 2 || d == -2
+}
+
+// Sides of castling returned by CastleSide and used in GameStats.
+const (
+	CastledKingside  = "kingside"
+	CastledQueenside = "queenside"
+	CastledNone      = "none"
+)
+
+// CastleSide returns the side of the castling move, CastledKingside or CastledQueenside.
+// The move has to be a castling (see IsCastle).
+func CastleSide(m move.Move) string {
+	// Squares are numbered from h1 to a8, so the king moves to lower squares when castling kingside.
+	if m.Destination < m.Source {
+		return CastledKingside
+	}
+	return CastledQueenside
+}
+
+// IsEnPassant reports whether the move is an en passant capture in the position.
+func IsEnPassant(pos *position.Position, m move.Move) bool {
+	return m.Destination == pos.EnPassant && pos.OnSquare(m.Source).Type == piece.Pawn && pos.OnSquare(m.Destination).Type == piece.None
+}
+
+// StatsAccumulator sums move types over many games.
+// Quiet moves are moves which are neither captures, castles nor promotions.
+// En passant captures are counted in Captures too. Checks are counted independently of the move type.
+type StatsAccumulator struct {
+	Games      int            `json:"games"`
+	HalfMoves  int            `json:"halfMoves"`
+	Quiet      int            `json:"quiet"`
+	Captures   int            `json:"captures"`
+	EnPassant  int            `json:"enPassant"`
+	Castles    int            `json:"castles"`
+	Checks     int            `json:"checks"`
+	Promotions map[string]int `json:"promotions"`
+	// CheckedGames is the number of games with a check and FirstCheckPlies the sum of their first check plies (see FirstCheckPly).
+	CheckedGames    int `json:"checkedGames"`
+	FirstCheckPlies int `json:"firstCheckPlies"`
+	// Mates is the number of games ending by checkmate (see MatingMove).
+	Mates int `json:"mates"`
+	// LegalMoves is the total number of legal moves in positions, where a move was played, and MaxBranching the highest number in one position (see Branching).
+	LegalMoves   int `json:"legalMoves"`
+	MaxBranching int `json:"maxBranching"`
+}
+
+// Names of promotion pieces in StatsAccumulator.Promotions.
+var promotionNames = map[piece.Type]string{
+	piece.Queen:  "queen",
+	piece.Rook:   "rook",
+	piece.Bishop: "bishop",
+	piece.Knight: "knight",
+}
+
+// AccumulateStats adds move types of the game to the accumulator by comparing consecutive positions.
+// The game has to have positions.
+func AccumulateStats(g *game.Game, acc *StatsAccumulator) {
+	if acc.Promotions == nil {
+		acc.Promotions = map[string]int{}
+	}
+	acc.Games += 1
+	for i := 1; i < len(g.Positions); i++ {
+		prev, cur := g.Positions[i-1], g.Positions[i]
+		m := cur.LastMove
+		acc.HalfMoves += 1
+		capture, castle := IsCapture(prev, m), IsCastle(prev, m)
+		if capture {
+			acc.Captures += 1
+		}
+		if IsEnPassant(prev, m) {
+			acc.EnPassant += 1
+		}
+		if castle {
+			acc.Castles += 1
+		}
+		if m.Promote != piece.None {
+			acc.Promotions[promotionNames[m.Promote]] += 1
+		}
+		if !capture && !castle && m.Promote == piece.None {
+			acc.Quiet += 1
+		}
+		if cur.Check(cur.ActiveColor) {
+			acc.Checks += 1
+		}
+	}
+	if ply, ok := FirstCheckPly(g); ok {
+		acc.CheckedGames += 1
+		acc.FirstCheckPlies += ply
+	}
+	if _, ok := MatingMove(g); ok {
+		acc.Mates += 1
+	}
+	total, max := Branching(g)
+	acc.LegalMoves += total
+	if max > acc.MaxBranching {
+		acc.MaxBranching = max
+	}
+}
+
+// WriteReport writes a human readable report of accumulated stats to w.
+func (acc *StatsAccumulator) WriteReport(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Games: %d\nHalf-moves: %d\nQuiet moves: %d\nCaptures: %d\nEn passant captures: %d\nCastles: %d\nChecks: %d\nPromotions: queen %d, rook %d, bishop %d, knight %d\n",
+		acc.Games, acc.HalfMoves, acc.Quiet, acc.Captures, acc.EnPassant, acc.Castles, acc.Checks,
+		acc.Promotions["queen"], acc.Promotions["rook"], acc.Promotions["bishop"], acc.Promotions["knight"])
+	if err != nil {
+		return err
+	}
+	avgFirstCheck := 0.0
+	if acc.CheckedGames > 0 {
+		avgFirstCheck = float64(acc.FirstCheckPlies) / float64(acc.CheckedGames)
+	}
+	avgBranching := 0.0
+	if acc.HalfMoves > 0 {
+		avgBranching = float64(acc.LegalMoves) / float64(acc.HalfMoves)
+	}
+	_, err = fmt.Fprintf(w, "Games with check: %d, average first check ply: %.1f\nCheckmates: %d\nBranching: average %.2f, max %d\n", acc.CheckedGames, avgFirstCheck, acc.Mates, avgBranching, acc.MaxBranching)
+	return err
+}
@@ -0,0 +1,124 @@
+package gen
+
+import (
+	"strings"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/position"
+)
+
+// Draw reasons returned by DrawReason.
+const (
+	DrawStalemate            = "stalemate"
+	DrawFiftyMoveRule        = "fifty-move rule"
+	DrawThreefoldRepetition  = "threefold repetition"
+	DrawInsufficientMaterial = "insufficient material"
+	DrawDeadPosition         = "dead position"
+	DrawNoProgress           = "no progress"
+)
+
+// Halfmove clock, at which the chess library draws games by the fifty-move rule automatically.
+// Stored games generated by it never have a higher halfmove clock.
+const fiftyMoveHalfMoves = 100
+
+// TagAdjudication is a game tag holding the draw reason for games declared drawn by the generator before the game status ended them.
+const TagAdjudication = "Adjudication"
+
+// GameStatus returns status of the game, taking adjudication by the generator into account.
+// Adjudicated games have the status of the draw reason, or game.Draw if there is no matching status.
+func GameStatus(g *game.Game) game.GameStatus {
+	switch g.Tags[TagAdjudication] {
+	case "":
+		return g.Status()
+	case DrawStalemate:
+		return game.Stalemate
+	case DrawFiftyMoveRule:
+		return game.FiftyMoveRule
+	case DrawThreefoldRepetition:
+		return game.ThreefoldRepetition
+	case DrawInsufficientMaterial:
+		return game.InsufficientMaterial
+	}
+	return game.Draw
+}
+
+// DrawReason returns the reason why the game ended in a draw, or empty string if the game is not a draw.
+// The reason is taken from adjudication or the game status. If the status doesn't tell, the final position and game history are inspected.
+// The chess library applies the fifty-move rule automatically as soon as the halfmove clock reaches fiftyMoveHalfMoves,
+// so games never reach the clock of the seventy-five-move rule and are reported as ended by the fifty-move rule (see HalfmoveClock).
+func DrawReason(g *game.Game) string {
+	if reason := g.Tags[TagAdjudication]; reason != "" {
+		return reason
+	}
+	gs := g.Status()
+	if gs&game.Draw == 0 || len(g.Positions) == 0 {
+		return ""
+	}
+	last := g.Positions[len(g.Positions)-1]
+	switch {
+	case gs&game.Stalemate != 0:
+		return DrawStalemate
+	case gs&game.InsufficientMaterial != 0:
+		return DrawInsufficientMaterial
+	case gs&game.ThreefoldRepetition != 0:
+		return DrawThreefoldRepetition
+	case gs&game.FiftyMoveRule != 0:
+		return DrawFiftyMoveRule
+	}
+
+	if len(last.LegalMoves()) == 0 && !last.Check(last.ActiveColor) {
+		return DrawStalemate
+	}
+	if maxRepetition(g) >= 3 {
+		return DrawThreefoldRepetition
+	}
+	if last.FiftyMoveCount >= fiftyMoveHalfMoves {
+		return DrawFiftyMoveRule
+	}
+	return DrawInsufficientMaterial
+}
+
+// HalfmoveClock returns the number of half-moves since the last capture or pawn move in the final position of the game, or -1 if the game has no positions.
+func HalfmoveClock(g *game.Game) int {
+	if len(g.Positions) == 0 {
+		return -1
+	}
+	return int(g.Positions[len(g.Positions)-1].FiftyMoveCount)
+}
+
+// Returns the highest number of occurrences of the same position in the game.
+// Positions are compared by piece placement, side to move, castling rights and en passant square.
+func maxRepetition(g *game.Game) int {
+	counts := map[string]int{}
+	max := 0
+	for _, pos := range g.Positions {
+		key, ok := repetitionKey(pos)
+		if !ok {
+			continue
+		}
+		counts[key] += 1
+		if counts[key] > max {
+			max = counts[key]
+		}
+	}
+	return max
+}
+
+// Returns key of the position, which is the same for repeated positions: piece placement, active color, castling rights and en passant square.
+func repetitionKey(pos *position.Position) (string, bool) {
+	s, err := fen.Encode(pos)
+	if err != nil {
+		return "", false
+	}
+	return strings.Join(strings.Fields(s)[:4], " "), true
+}
+
+// IsDecisive reports whether one side won the game.
+// Games loaded from storage without positions are decisive, if the last SAN move is a checkmate.
+func IsDecisive(g *game.Game) bool {
+	if len(g.Positions) == 0 {
+		return strings.HasSuffix(g.Tags["sanMoves"], "#")
+	}
+	return GameStatus(g)&(game.WhiteWon|game.BlackWon) != 0
+}
@@ -0,0 +1,40 @@
+package gen
+
+import (
+	"context"
+	"time"
+)
+
+// Stream generates random games for seeds received from the channel and emits them on the returned channel in the same order.
+// The returned channel is closed when seeds channel is closed or ctx is done.
+func Stream(ctx context.Context, seeds <-chan int64) <-chan GameResult {
+	return GenerateStream(ctx, seeds, Options{})
+}
+
+// GenerateStream is like Stream, but games are generated with opts.
+func GenerateStream(ctx context.Context, seeds <-chan int64, opts Options) <-chan GameResult {
+	out := make(chan GameResult)
+	go func() {
+		defer close(out)
+		for {
+			var seed int64
+			select {
+			case <-ctx.Done():
+				return
+			case s, ok := <-seeds:
+				if !ok {
+					return
+				}
+				seed = s
+			}
+			start := time.Now()
+			g, err := GenerateContext(ctx, seed, opts)
+			select {
+			case <-ctx.Done():
+				return
+			case out <- GameResult{seed, g, err, time.Since(start)}:
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,32 @@
+package gen
+
+import (
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/andrewbackes/chess/position/move"
+)
+
+// Lowercase promotion suffixes of UCI moves.
+var uciPromotions = map[piece.Type]string{
+	piece.Queen:  "q",
+	piece.Rook:   "r",
+	piece.Bishop: "b",
+	piece.Knight: "n",
+}
+
+// UCIMove returns the move in long algebraic notation used by UCI, e.g. "e2e4" or "e7e8q".
+// Castling is written as the king move, e.g. "e1g1".
+func UCIMove(m move.Move) string {
+	return m.Source.String() + m.Destination.String() + uciPromotions[m.Promote]
+}
+
+// UCIMoves returns moves of the game in long algebraic notation used by UCI.
+func UCIMoves(g *game.Game) []string {
+	uciMoves := make([]string, 0, len(g.Positions)-1)
+	for i := range g.Positions {
+		if g.Positions[i].LastMove != move.Null {
+			uciMoves = append(uciMoves, UCIMove(g.Positions[i].LastMove))
+		}
+	}
+	return uciMoves
+}
@@ -2,206 +2,1015 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
-	"math/rand"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/andrewbackes/chess/game"
-	"github.com/andrewbackes/chess/position"
-	"github.com/andrewbackes/chess/position/move"
+	"github.com/jezek/chess-game-generator/gen"
+	"github.com/jezek/chess-game-generator/storage"
 )
 
-// Stores games with half-moves closest to key value.
-var gamesOfLength = map[int]*game.Game{
-	10:  nil,
-	25:  nil,
-	50:  nil,
-	100: nil,
-	250: nil,
-	500: nil,
-	750: nil,
-}
+// Stores games with half-moves closest to target values, in one or more named collectors.
+var gamesOfLength *gen.CollectorSet
+
+// Default targets of gamesOfLength.
+const defaultTargets = "10,25,50,100,250,500,750"
+
+// Default number of games to be generated.
+// Note: Tried to 10000, but for default targets, 1500 is enough.
+const defaultSearches = 10000
+
+// errStop is returned from generation callback to stop generation without failure.
+var errStop = errors.New("stop generation")
+
+// Number of seeds generated at once, when generating for a duration.
+const durationBatch = 1000
+
+// errInterrupted is returned from generation, when it is stopped because the program receives an interrupt signal.
+var errInterrupted = errors.New("interrupted")
 
 func main() {
-	// Number games to be generated with seeds from 0 to noSearches-1, to find games of certain length.
-	// Note: Tried to 10000, but for following gamesOfLength keys, 1500 is enough.
-	noSearches := 10000
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags]\n       %s diff <storage-a> <storage-b>\n       %s [-storage file] show <seed>\n       %s merge [-policy longer|first|last] <out> <storage>...\n\n", os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Generates random chess games and selects games with half-moves closest to target lengths.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Subcommand diff reports seeds of games added, deleted and modified between two storage files.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Subcommand show replays a stored game and prints board and FEN after every move.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Subcommand merge merges storage files into a new storage file sorted by seed.\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	noSearches := flag.Int("searches", defaultSearches, "Number of games to generate, with seeds from 0 to searches-1, to find games of target lengths.")
+	seedOffset := flag.Int64("seed-offset", 0, "First seed of generated games, so games with seeds from seed-offset to seed-offset+searches-1 are generated, e.g. to cover disjoint seed ranges on more machines. Storage files always hold absolute seeds.")
+	shardFlag := flag.String("shard", "1/1", "Generate only seeds of this shard, e.g. \"2/5\" generates seeds with seed % 5 == 1, to distribute seeds across machines without overlap. Storage files of shards can be merged by the merge subcommand.")
+	seedList := flag.String("seeds", "", "Comma separated list of seeds to generate games for, e.g. \"42,1000000,9999999999\", or \"-\" to read seeds from stdin, one per line, skipping blank lines and \"#\" comments. If set, -searches is ignored and only games for these seeds are considered.")
+	targetList := flag.String("targets", defaultTargets, "Comma separated list of target half-move lengths. Game closest to each target is selected. Targets prefixed with \"=\" (e.g. \"=50\") accept only games of exactly that length and generation stops early when all exact targets are filled. Targets \"longest\", \"shortest\" and \"longest-mate\" report the longest game, the shortest decisive game and the longest game ending by checkmate. Targets prefixed with \"p\" (e.g. \"p25,p50,p75\") are percentiles of lengths of all considered games, they need all games generated first, so they are resolved to lengths and filled only after generation ends and turn off abandoning games by -adaptive-bail.")
+	format := flag.String("format", "go", "Format of the result file: "+formatsUsage)
+	storageFileName := flag.String("storage", "./generateStorage.txt", "Storage file for generated games. Games in storage are not generated again. If empty, games are neither loaded nor stored.")
+	outFileName := flag.String("out", "", "Result file. If empty, \"./generated_<searches>.txt\" is used, or \"./generated_<name>_<searches>.txt\" for named collectors. With named collectors, \"{name}\" in the file name is replaced by the collector name.")
+	outSuffix := flag.String("out-suffix", "", "Suffix appended with \"_\" to result file names before the extension, e.g. \"generated_10000_2024-06-01.txt\" for \"{date}\". \"{date}\" in the suffix is replaced by the current date. If empty, no suffix is appended.")
+	tieBreakFlag := flag.String("tie-break", "seed", "Policy choosing between games equally distant from a target: \"seed\" prefers lower seed, which is the first generated game, \"shorter\" the shorter game, \"longer\" the longer game and \"captures\" the game with more captures. Games equal by the policy are decided by lower seed.")
+	collectorFlags := namedTargets{}
+	flag.Var(&collectorFlags, "collector", "Named collector with its own targets and result file, e.g. \"short=5,10,20\". Can be repeated, every game is offered to all collectors. If set, -targets is ignored.")
+	picker := flag.String("picker", "uniform", "Move picker: \"uniform\" picks every legal move with the same probability, \"captures\" picks captures 3 times more likely than quiet moves. \"central\" picks moves to the center 3 times more likely than moves outside the extended center. \"first\" always picks the move with alphabetically first SAN. Storage files hold games generated with one set of pickers and options changing moves, so use another -storage file for other pickers.")
+	whitePicker := flag.String("white-picker", "", "Move picker of White, with the same values as -picker. If empty, -picker picks moves of White.")
+	blackPicker := flag.String("black-picker", "", "Move picker of Black, with the same values as -picker. If empty, -picker picks moves of Black.")
+	noEarlyQueen := flag.Int("no-early-queen", 0, "Don't move queens in the first this number of half-moves, unless only queen moves are legal. Works with any -picker. 0 turns it off.")
+	maxHalfMoves := flag.Int("max-half-moves", 0, "Stop generated games after this number of half-moves and mark them truncated. Truncated games are not stored. 0 means unlimited.")
+	stopAtPly := flag.Int("stop-at-ply", 0, "Stop generated games after this number of half-moves regardless of their status, e.g. to generate openings, and mark them truncated. Truncated games are not stored. 0 means not stopping.")
+	startFEN := flag.String("fen", "", "FEN of the starting position of generated games. If empty, the initial position is used. Storage files hold games of one starting position, so use another -storage file for every FEN.")
+	progressEvery := flag.Int("progress", 100, "Log progress every this number of finished seeds, counting generated, abandoned and failed games. 0 turns progress off and logs every generated game instead.")
+	strict := flag.Bool("strict", false, "Exit on the first seed failing to generate a game. Otherwise failing seeds are logged, skipped and reported at the end.")
+	strictStorage := flag.Bool("strict-storage", false, "Generate selected games loaded from storage again from their seed and exit, if their moves differ from stored moves.")
+	validate := flag.Bool("validate", false, "Validate storage by replaying every stored game, instead of only checking the number of moves.")
+	dedup := flag.Bool("dedup", false, "Skip games with the same moves as an already seen game. Skipped games are neither selected nor stored.")
+	avoidRepetition := flag.Bool("avoid-repetition", false, "Don't play moves leading to a position already seen twice in the game, unless there is no other legal move. Such games don't reproduce games generated without this flag.")
+	drawAfterQuiet := flag.Int("draw-after-quiet", 0, "Declare games drawn after this number of half-moves without a capture or a pawn move. Such games don't reproduce games generated without this flag and games drawn this way are not stored. 0 turns it off.")
+	stopDead := flag.Bool("stop-dead", false, "Declare games drawn as soon as their position is a dead draw with kings behind a locked pawn chain. Such games don't reproduce games generated without this flag.")
+	stopInsufficient := flag.Bool("stop-insufficient", false, "Declare games drawn as soon as neither side has enough material to checkmate. Such games don't reproduce games generated without this flag.")
+	idTemplate := flag.String("id-template", defaultIDTemplate, "Go template of result identifiers in Go literal, UCI and figurine formats. Available fields are .Seed, .HalfMoves and .Target.")
+	materialBalance := flag.Bool("material-balance", false, "Add material balance in centipawns from White's perspective of every position to results in JSON format.")
+	statsFileName := flag.String("stats", "", "Write move type statistics of games generated in this run to this file. \"-\" writes to stderr. If empty, no statistics are computed.")
+	storageFormat := flag.String("storage-format", "text", "Format of new storage files: \"text\" for space separated lines or \"jsonl\" for JSON lines. Format of existing storage files is detected.")
+	compress := flag.Bool("compress", false, "Read and write storage file gzip compressed. Storage files with \".gz\" extension are always compressed.")
+	terminal := flag.String("terminal", "any", "Consider only games ending with terminal status: \"checkmate\", \"stalemate\", \"draw\" (any draw, including stalemate) or \"any\". Other games are neither selected nor stored.")
+	require := flag.String("require", "", "Consider only games containing required features. Comma separated features are \"promotion\" and \"castling\" for games where both sides castled. Other games are neither selected nor stored.")
+	selfCheck := flag.Bool("selfcheck", false, "Verify that replaying SAN moves of every generated game reproduces the same positions. Games failing the check are neither selected nor stored.")
+	storageAttempts := flag.Int("storage-attempts", 5, "Number of attempts to write and sync storage file, with exponentially growing delay between them, before exiting with failure.")
+	skipBadLines := flag.Bool("skip-bad-lines", false, "Log and skip malformed storage lines instead of exiting. Games for skipped lines are generated again.")
+	farThreshold := flag.Float64("far-threshold", 0.5, "Warn about targets, whose closest game differs from the target by more than this fraction of the target. 0 turns warnings off.")
+	histogramFileName := flag.String("histogram", "", "Write histogram of half-move lengths of all considered games, including games loaded from storage, to this file. \"-\" writes to stderr. If empty, no histogram is computed.")
+	histogramWidth := flag.Int("histogram-width", 10, "Width of histogram buckets in half-moves.")
+	duration := flag.Duration("duration", 0, "Generate games with increasing seeds from 0 until this time elapses (e.g. \"30s\"), then write results. Games in storage are not generated again. If set, -searches is ignored and the number of seeds reached is used in the result file name.")
+	profileSeeds := flag.Int("profile-seeds", 0, "Report this number of seeds, whose games took the longest time to generate, with their lengths and durations. 0 turns profiling off.")
+	bailBelow := flag.Int("bail-below", 0, "Abandon generation of games, as soon as they can't reach this number of half-moves. Abandoned games are neither selected nor stored. 0 turns it off.")
+	adaptiveBail := flag.Bool("adaptive-bail", false, "Abandon generation of games, as soon as they can't reach the length of a game, which could still be selected for any target. The threshold grows as targets are filled, at least to -bail-below. Abandoned games are not stored. Results are the same as without it, but statistics and histogram miss abandoned games.")
+	verbosityFlag := flag.String("v", "normal", "Verbosity of logs: \"quiet\" logs only errors, warnings and the final summary, \"normal\" adds progress and selected games, \"verbose\" adds every generated game and stored moves differing from generated moves.")
+	augment := flag.String("augment", "", "Add augmented games to results after every selected game: \"mirror\" adds the game with files a and h swapped, starting from the mirrored position. Games with castling can't be mirrored. If empty, no games are added.")
+	maxMaterialSwing := flag.Int("max-material-swing", 0, "Discard games, whose material balance ever exceeds this number of pawns for either side. Generation of such games is abandoned as soon as it is exceeded and they are not stored, stored games exceeding it are not selected. 0 means no limit.")
+	minLength := flag.Int("min-length", 0, "Discard games shorter than this number of half-moves right after generation. Discarded games are neither selected nor stored. 0 turns it off.")
+	pgnClock := flag.Bool("pgn-clock", false, "Write a synthetic \"{[%clk 0:00:00]}\" clock comment after every move of PGN results, for GUIs requiring clock data. Games are generated instantly, so clocks are always zero.")
+	pgnFENs := flag.Bool("pgn-fens", false, "Write a comment with FEN of the resulting position after every move of PGN results. It enlarges PGN results many times.")
+	splitOutput := flag.String("split-output", "", "Also write every selected game as PGN to its own file \"target-<N>_seed-<S>.pgn\" in this directory, which is created if needed. If empty, no such files are written.")
+	appendResults := flag.Bool("append-results", false, "Append results to the result file after a comment line with the date of the run and the range of seeds, instead of replacing the file. Only \"go\" and \"pgn\" formats can be appended.")
+	dryRun := flag.Bool("dry-run", false, "Generate or load games and log selected games for targets, but don't write result, statistics nor storage files.")
+	workers := flag.Int("workers", runtime.NumCPU(), "Number of goroutines generating games in parallel. Games are processed in the order of seeds and ties are broken by seed, so result files are the same for any number of workers, including 1 for sequential generation. Only where -duration stops, and which games -adaptive-bail abandons and doesn't store, depend on timing.")
+	flag.Parse()
+	subcommands := map[string]func(args []string){
+		"diff":  runDiff,
+		"merge": runMerge,
+		"show":  func(args []string) { runShow(*storageFileName, args) },
+	}
+	if run, ok := subcommands[flag.Arg(0)]; ok {
+		run(flag.Args()[1:])
+		return
+	}
+	if flag.NArg() > 0 {
+		log.Fatalf("Unknown subcommand %q", flag.Arg(0))
+	}
+	switch *verbosityFlag {
+	case "quiet":
+		verbosity = levelQuiet
+	case "normal":
+		verbosity = levelNormal
+	case "verbose":
+		verbosity = levelVerbose
+	default:
+		log.Fatalf("Unknown verbosity \"%s\"", *verbosityFlag)
+	}
+	if *noSearches <= 0 {
+		log.Fatalf("Number of searches must be positive, got %d", *noSearches)
+	}
+	if !validFormat(*format) {
+		log.Fatalf("Unknown result format \"%s\"", *format)
+	}
+	idTmpl, err := parseIDTemplate(*idTemplate)
+	if err != nil {
+		log.Fatalf("Error parsing result identifier template: %v", err)
+	}
+	if _, ok := commentPrefixes[*format]; *appendResults && !ok {
+		log.Fatalf("Results in format \"%s\" can't be appended", *format)
+	}
+	if *augment != "" && *augment != "mirror" {
+		log.Fatalf("Unknown augmentation \"%s\"", *augment)
+	}
+	rw := resultWriter{format: *format, idTemplate: idTmpl, pgn: gen.PGNOptions{Clock: *pgnClock, EmbedFENs: *pgnFENs}}
+	if *maxHalfMoves < 0 {
+		log.Fatalf("Maximum of half-moves can't be negative, got %d", *maxHalfMoves)
+	}
+	if *noEarlyQueen < 0 {
+		log.Fatalf("Ply of early queen moves can't be negative, got %d", *noEarlyQueen)
+	}
+	if *stopAtPly < 0 {
+		log.Fatalf("Ply to stop at can't be negative, got %d", *stopAtPly)
+	}
+	if *duration < 0 {
+		log.Fatalf("Duration can't be negative, got %v", *duration)
+	}
+	if *maxMaterialSwing < 0 {
+		log.Fatalf("Material swing can't be negative, got %d", *maxMaterialSwing)
+	}
+	if *drawAfterQuiet < 0 {
+		log.Fatalf("Number of quiet half-moves can't be negative, got %d", *drawAfterQuiet)
+	}
+	if *seedOffset != 0 && *seedList != "" {
+		log.Fatal("Flags -seed-offset and -seeds can't be used together")
+	}
+	if *duration > 0 && *seedList != "" {
+		log.Fatal("Flags -duration and -seeds can't be used together")
+	}
+	if *storageFormat != "text" && *storageFormat != "jsonl" {
+		log.Fatalf("Unknown storage format \"%s\"", *storageFormat)
+	}
+	if *storageAttempts < 1 {
+		log.Fatalf("Number of storage attempts must be positive, got %d", *storageAttempts)
+	}
+	if *farThreshold < 0 {
+		log.Fatalf("Far from target threshold can't be negative, got %v", *farThreshold)
+	}
+	if *profileSeeds < 0 {
+		log.Fatalf("Number of profiled seeds can't be negative, got %d", *profileSeeds)
+	}
+	if *bailBelow < 0 {
+		log.Fatalf("Length to bail below can't be negative, got %d", *bailBelow)
+	}
+	if *histogramWidth < 1 {
+		log.Fatalf("Histogram bucket width must be positive, got %d", *histogramWidth)
+	}
+	if *progressEvery < 0 {
+		log.Fatalf("Progress interval can't be negative, got %d", *progressEvery)
+	}
+	if *startFEN != "" {
+		if err := gen.ValidateFEN(*startFEN); err != nil {
+			log.Fatal(err)
+		}
+	}
+	tieBreak, ok := tieBreaks[*tieBreakFlag]
+	if !ok {
+		log.Fatalf("Unknown tie break \"%s\"", *tieBreakFlag)
+	}
+	gamesOfLength = gen.NewCollectorSet()
+	if len(collectorFlags) == 0 {
+		c, err := parseTargets(*targetList)
+		if err != nil {
+			log.Fatalf("Error parsing targets: %v", err)
+		}
+		c.SetTieBreak(tieBreak)
+		gamesOfLength.Register("", c)
+	}
+	for _, nt := range collectorFlags {
+		c, err := parseTargets(nt.targets)
+		if err != nil {
+			log.Fatalf("Error parsing targets of collector %q: %v", nt.name, err)
+		}
+		c.SetTieBreak(tieBreak)
+		if err := gamesOfLength.Register(nt.name, c); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if len(collectorFlags) > 1 && *outFileName != "" && !strings.Contains(*outFileName, "{name}") {
+		log.Fatalf("Result file name %q has to contain \"{name}\" for multiple collectors", *outFileName)
+	}
+	filters := gameFilters{}
+	if f := materialSwingFilter(*maxMaterialSwing * 100); f != nil {
+		filters = append(filters, f)
+	}
+	if f := minLengthFilter(*minLength); f != nil {
+		filters = append(filters, f)
+	}
+	if f, err := terminalFilter(*terminal); err != nil {
+		log.Fatalf("Error parsing terminal filter: %v", err)
+	} else if f != nil {
+		filters = append(filters, f)
+	}
+	if f, err := requireFilter(*require); err != nil {
+		log.Fatalf("Error parsing required features: %v", err)
+	} else if f != nil {
+		filters = append(filters, f)
+	}
+	var seeds []int64
+	if *seedList == "-" {
+		seeds, err = readSeeds(os.Stdin)
+	} else {
+		seeds, err = parseSeeds(*seedList)
+	}
+	if err != nil {
+		log.Fatalf("Error parsing seeds: %v", err)
+	}
+	if seeds == nil {
+		seeds = make([]int64, 0, *noSearches)
+		for i := 0; i < *noSearches; i += 1 {
+			seeds = append(seeds, *seedOffset+int64(i))
+		}
+	}
+	shard, shards, err := parseShard(*shardFlag)
+	if err != nil {
+		log.Fatalf("Error parsing shard: %v", err)
+	}
+	if shards > 1 {
+		sharded := make([]int64, 0, len(seeds)/shards+1)
+		for _, seed := range seeds {
+			if inShard(seed, shard, shards) {
+				sharded = append(sharded, seed)
+			}
+		}
+		seeds = sharded
+	}
+	opts := gen.Options{MaxHalfMoves: *maxHalfMoves, StopAtPly: *stopAtPly, FEN: *startFEN, StopOnInsufficientMaterial: *stopInsufficient, AvoidRepetition: *avoidRepetition, StopOnDeadDraw: *stopDead, DrawAfterQuiet: *drawAfterQuiet, MaxMaterialSwing: *maxMaterialSwing * 100}
+	opts.Picker, err = parsePicker(*picker)
+	if err != nil {
+		log.Fatalf("Error parsing picker: %v", err)
+	}
+	if *whitePicker != "" {
+		if opts.WhitePicker, err = parsePicker(*whitePicker); err != nil {
+			log.Fatalf("Error parsing white picker: %v", err)
+		}
+		if opts.WhitePicker == nil {
+			opts.WhitePicker = gen.UniformPicker
+		}
+	}
+	if *blackPicker != "" {
+		if opts.BlackPicker, err = parsePicker(*blackPicker); err != nil {
+			log.Fatalf("Error parsing black picker: %v", err)
+		}
+		if opts.BlackPicker == nil {
+			opts.BlackPicker = gen.UniformPicker
+		}
+	}
+	// Minimal length of useful games, updated from gamesOfLength in the order of seeds, but read concurrently by generating goroutines.
+	// It only grows, so games abandoned because of it would not be selected when delivered.
+	minUseful := int64(*bailBelow)
+	updateMinUseful := func() {
+		if *adaptiveBail {
+			if m := int64(gamesOfLength.MinUseful()); m > atomic.LoadInt64(&minUseful) {
+				atomic.StoreInt64(&minUseful, m)
+			}
+		}
+	}
+	if *bailBelow > 0 || *adaptiveBail {
+		opts.BailBelow = func() int {
+			return int(atomic.LoadInt64(&minUseful))
+		}
+	}
+	if *noEarlyQueen > 0 {
+		opts.Picker = gen.FilteredPicker(opts.Picker, gen.NoEarlyQueen(*noEarlyQueen))
+		if opts.WhitePicker != nil {
+			opts.WhitePicker = gen.FilteredPicker(opts.WhitePicker, gen.NoEarlyQueen(*noEarlyQueen))
+		}
+		if opts.BlackPicker != nil {
+			opts.BlackPicker = gen.FilteredPicker(opts.BlackPicker, gen.NoEarlyQueen(*noEarlyQueen))
+		}
+	}
+
+	// Offers the game to gamesOfLength and reports whether it was accepted, or skipped as filtered or duplicate.
+	seen := map[string]bool{}
+	duplicates := 0
+	histogram := gen.NewLengthHistogram(*histogramWidth)
+	collect := func(g *game.Game) bool {
+		if *histogramFileName != "" {
+			histogram.Add(gen.GameLength(g))
+		}
+		if !filters.accept(g) {
+			return false
+		}
+		if *dedup {
+			h := gen.GameHash(g)
+			if seen[h] {
+				duplicates += 1
+				return false
+			}
+			seen[h] = true
+		}
+		gamesOfLength.Add(g)
+		return true
+	}
 
+	listed := make(map[int64]bool, len(seeds))
+	for _, seed := range seeds {
+		listed[seed] = true
+	}
 	// Get generated games from storage.
-	storageFileName := "./generateStorage.txt"
-	f, err := os.OpenFile(storageFileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-	if err != nil {
-		log.Printf("Error opening/creating storage file: %v", err)
+	var st *storage.Storage
+	stored := map[int64]bool{}
+	if *storageFileName != "" {
+		var err error
+		st, err = storage.Open(*storageFileName, *compress, *dryRun)
+		if *dryRun && errors.Is(err, os.ErrNotExist) {
+			st = nil
+		} else if err != nil {
+			log.Fatalf("Error opening/creating storage file: %v", err)
+		}
 	}
-	scanner := bufio.NewScanner(f)
-	startIndex := 0
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Split(line, " ")
-		n, err := strconv.Atoi(parts[0])
+	if st != nil {
+		defer st.Close()
+		st.SkipBadLines = *skipBadLines
+		st.Attempts = *storageAttempts
+		st.JSONL = *storageFormat == "jsonl"
+		st.FEN = *startFEN
+		st.Options = storageOptions(*picker, *whitePicker, *blackPicker, *noEarlyQueen, *avoidRepetition, *stopInsufficient, *stopDead)
+		st.Logf = func(format string, v ...interface{}) { logf(levelNormal, format, v...) }
+		var err error
+		stored, err = st.Load(*validate, func(g *game.Game) {
+			if *seedList != "" {
+				// Only games for listed seeds are considered.
+				if seed, _ := gen.GameSeed(g); !listed[seed] {
+					return
+				}
+			}
+			collect(g)
+		})
 		if err != nil {
-			log.Printf("Error parsing storage line %d: %v", startIndex, err)
-			log.Fatalf("Storage file \"%s\" is corrupt. Repair or remove it and restart tests.", storageFileName)
-		}
-		moves := parts[1:]
-		if n != len(moves) {
-			log.Printf("Error quick validating storage line %d: %s", startIndex, fmt.Sprint("number of moves ", n, " does not correspond to umber of SAN moves ", len(moves)))
-			log.Fatalf("Storage file \"%s\" is corrupt. Repair or remove it and restart tests.", storageFileName)
-		}
-		g := &game.Game{
-			Tags: map[string]string{
-				"#":        fmt.Sprint(startIndex),
-				"sanMoves": strings.Join(moves, " "),
-			},
-			Positions: make([]*position.Position, 0, n+1),
-		}
-		addToGamesOfLength(g)
-		startIndex += 1
-		if startIndex >= noSearches {
+			log.Fatalf("Error loading storage file \"%s\": %v", *storageFileName, err)
+		}
+	}
+
+	// Generate new games, which are not in storage yet, and store them.
+	// With -duration, seeds are generated in batches from -seed-offset until time is up,
+	// so only seeds loaded from storage are skipped.
+	missing := []int64{}
+	for _, seed := range seeds {
+		if *duration > 0 {
 			break
 		}
+		if !stored[seed] {
+			missing = append(missing, seed)
+			stored[seed] = true
+		}
+	}
+	if gamesOfLength.ExactFilled() {
+		missing = nil
+	}
+	// Stop generating on interrupt, so storage is flushed and closed properly.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stats := gen.StatsAccumulator{}
+	var generated func(gen.GameResult) error
+	profiles := seedProfiles{}
+	bailed := 0
+	swung := 0
+	failedSeeds := []int64{}
+	updateMinUseful()
+	var deadline time.Time
+	if *duration > 0 {
+		deadline = time.Now().Add(*duration)
+		logf(levelNormal, "Generating games for %v using %d workers", *duration, *workers)
+	} else {
+		logf(levelNormal, "Generating %d games using %d workers", len(missing), *workers)
+	}
+	var prog *progress
+	if *progressEvery > 0 {
+		prog = newProgress(len(missing), *progressEvery)
+		prog.deadline = deadline
+	}
+	lastSeed := *seedOffset - 1
+	generate := func(seeds []int64) error {
+		// Games generated after interrupt are not delivered, so they are not stored.
+		err := gen.GenerateParallel(ctx, seeds, *workers, opts, generated)
+		if err != nil && ctx.Err() != nil {
+			return errInterrupted
+		}
+		return err
+	}
+	generated = func(r gen.GameResult) error {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			logf(levelNormal, "Time is up after game with seed #%d", lastSeed)
+			return errStop
+		}
+		lastSeed = r.Seed
+		if errors.Is(r.Err, gen.ErrBailed) {
+			bailed += 1
+			prog.add(outcomeAbandoned)
+			return nil
+		}
+		if errors.Is(r.Err, gen.ErrMaterialSwing) {
+			swung += 1
+			prog.add(outcomeAbandoned)
+			return nil
+		}
+		if r.Err != nil {
+			if *strict {
+				log.Fatalf("Error generating game with seed #%d: %v", r.Seed, r.Err)
+			}
+			log.Printf("Error generating game with seed #%d, skipping it: %v", r.Seed, r.Err)
+			failedSeeds = append(failedSeeds, r.Seed)
+			prog.add(outcomeFailed)
+			return nil
+		}
+		g := r.Game
+		if *profileSeeds > 0 {
+			profiles.add(r.Seed, len(g.Positions)-1, r.Duration)
+		}
+		prog.add(outcomeGenerated)
+		if prog == nil || verbosity >= levelVerbose {
+			logf(levelNormal, "Generated game with seed #%d | GameStatus after %d half-moves: %v%s", r.Seed, len(g.Positions)-1, gen.GameStatus(g), statusLog(g))
+		}
+		if *selfCheck {
+			if err := gen.VerifyReplay(g); err != nil {
+				log.Printf("Error self-checking game with seed #%d: %v", r.Seed, err)
+				return nil
+			}
+		}
+		if *statsFileName != "" {
+			gen.AccumulateStats(g, &stats)
+		}
+		if !collect(g) {
+			return nil
+		}
+		updateMinUseful()
+		if st != nil && storage.Storable(g) {
+			if err := st.Store(g); err != nil {
+				return err
+			}
+		}
+		if gamesOfLength.ExactFilled() {
+			logf(levelNormal, "All exact targets filled after game with seed #%d", r.Seed)
+			return errStop
+		}
+		return nil
+	}
+	if deadline.IsZero() {
+		err = generate(missing)
+	} else if !gamesOfLength.ExactFilled() {
+		// Generate batches of seeds, which are not in storage yet, until time is up.
+		next := *seedOffset
+		for err == nil {
+			batch := make([]int64, 0, durationBatch)
+			for ; len(batch) < durationBatch; next += 1 {
+				if !stored[next] && inShard(next, shard, shards) {
+					batch = append(batch, next)
+				}
+			}
+			err = generate(batch)
+		}
+		*noSearches = int(lastSeed + 1 - *seedOffset)
+	}
+	if err == errInterrupted {
+		log.Print("Interrupted, generated games are stored, no results are written")
+		return
+	}
+	if err != nil && err != errStop {
+		// Deferred functions don't run on exit, so storage is closed first, to keep what was stored.
+		log.Printf("Error storing generated games: %v", err)
+		if st != nil {
+			st.Close()
+		}
+		os.Exit(1)
+	}
+	stop()
+	for _, name := range gamesOfLength.Names() {
+		c := gamesOfLength.Collector(name)
+		resolved := c.ResolvePercentiles()
+		percentiles := make([]int, 0, len(resolved))
+		for p := range resolved {
+			percentiles = append(percentiles, p)
+		}
+		sort.Ints(percentiles)
+		for _, p := range percentiles {
+			logf(levelNormal, "Percentile %d%s of game lengths: %d half-moves", p, collectorLog(name), resolved[p])
+		}
+		if unfilled := c.Unfilled(); len(unfilled) > 0 {
+			log.Printf("No game found for targets%s: %v", collectorLog(name), unfilled)
+		}
+		for _, extreme := range []struct {
+			name string
+			g    *game.Game
+		}{{"Longest game", c.Longest()}, {"Shortest decisive game", c.Shortest()}, {"Longest mate", c.LongestMate()}} {
+			if extreme.g != nil {
+				log.Printf("%s%s: random game #%s | half-moves: %d", extreme.name, collectorLog(name), extreme.g.Tags["#"], gen.GameLength(extreme.g))
+			}
+		}
+		if *farThreshold > 0 {
+			for _, t := range c.FarFromTarget(*farThreshold) {
+				log.Printf("Warning: closest game for target %d%s has %d half-moves, more than %.0f%% away from target. Increase -searches to find closer games.", t, collectorLog(name), gen.GameLength(c.Game(t)), *farThreshold*100)
+			}
+		}
+	}
+	if *dedup {
+		log.Printf("Skipped %d duplicate games", duplicates)
 	}
-	if err := scanner.Err(); err != nil {
-		log.Printf("Error reading storage file: %v", err)
+	filters.report()
+	if opts.BailBelow != nil {
+		log.Printf("Abandoned %d games too short to be selected", bailed)
+	}
+	if len(failedSeeds) > 0 {
+		log.Printf("Failed to generate games for %d seeds: %v", len(failedSeeds), failedSeeds)
+	}
+	if opts.MaxMaterialSwing > 0 {
+		log.Printf("Abandoned %d generated games exceeding material swing of %d pawns", swung, *maxMaterialSwing)
+	}
+	if *profileSeeds > 0 {
+		profiles.report(*profileSeeds)
+	}
+	if *statsFileName != "" && !*dryRun {
+		if err := writeReport(*statsFileName, stats.WriteReport); err != nil {
+			log.Printf("Error writing statistics: %v", err)
+		}
+	}
+	if *histogramFileName != "" && !*dryRun {
+		if err := writeReport(*histogramFileName, histogram.WriteReport); err != nil {
+			log.Printf("Error writing histogram: %v", err)
+		}
 	}
 
-	// Generate new games and store them.
-	writer := bufio.NewWriter(f)
-	for i := startIndex; i < noSearches; i += 1 {
-		log.Print("Generating game with seed #", i)
-		g, err := generateRandomGame(i)
-		if err != nil {
-			log.Fatal(err)
+	// Compute results and save to files.
+	runComment := fmt.Sprintf("Run %s, seeds %s", time.Now().Format(time.RFC3339), seedRange(seeds))
+	if !deadline.IsZero() {
+		runComment = fmt.Sprintf("Run %s, seeds %d-%d", time.Now().Format(time.RFC3339), *seedOffset, lastSeed)
+	}
+	for _, name := range gamesOfLength.Names() {
+		results := selectResults(gamesOfLength.Collector(name), opts, *strictStorage)
+		if *augment == "mirror" {
+			results = mirrorResults(results)
+		}
+		if *materialBalance {
+			for i := range results {
+				results[i].MaterialBalance = gen.MaterialBalances(results[i].Game)
+			}
+		}
+		if *dryRun {
+			logf(levelNormal, "Dry run, not writing results to: %s", resultFileName(*outFileName, name, *noSearches, *outSuffix))
+			continue
+		}
+		write := writeResultFile
+		if *appendResults {
+			write = func(fileName string, rw resultWriter, results []gen.Result) error {
+				return appendResultFile(fileName, rw, results, runComment)
+			}
+		}
+		if err := write(resultFileName(*outFileName, name, *noSearches, *outSuffix), rw, results); err != nil {
+			log.Printf("Error writing results%s: %v", collectorLog(name), err)
 		}
-		log.Printf("GameStatus after %d half-moves: %v", len(g.Positions)-1, g.Status())
-		storeGame(writer, g)
-		if err := f.Sync(); err != nil {
-			log.Printf("Error syncing storage to disk: %v", err)
-			return
+		if *splitOutput != "" {
+			if err := writeSplitResults(*splitOutput, results, rw.pgn); err != nil {
+				log.Printf("Error writing split results%s: %v", collectorLog(name), err)
+			}
 		}
 	}
-	f.Close()
+}
 
-	// Compute results and save to file.
-	resultFileName := fmt.Sprintf("./generated_%d.txt", noSearches)
-	f, err = os.OpenFile(resultFileName, os.O_WRONLY|os.O_CREATE, 0666)
-	if err != nil {
-		log.Printf("Error creating result file: %v", err)
-	} else {
-		defer f.Close()
-	}
-	writer = bufio.NewWriter(f)
-	log.Printf("Writing results to: %s", resultFileName)
-	lengths := make([]int, 0, len(gamesOfLength))
-	for l, _ := range gamesOfLength {
-		lengths = append(lengths, l)
-	}
-	sort.Slice(lengths, func(i, j int) bool {
-		return lengths[i] < lengths[j]
-	})
-	for _, l := range lengths {
-		g := gamesOfLength[l]
+// selectResults returns results for the games selected by the collector.
+// Games loaded from storage are reconstructed first (see loadedGame).
+// If strict is true, games loaded from storage are always generated again from their seed and moves different from stored moves are fatal.
+func selectResults(c *gen.LengthCollector, opts gen.Options, strict bool) []gen.Result {
+	results := []gen.Result{}
+	for _, l := range c.Targets() {
+		g := c.Game(l)
+		if g == nil {
+			continue
+		}
 		if len(g.Positions) == 0 {
-			seed, err := strconv.Atoi(g.Tags["#"])
-			if err != nil {
-				log.Printf("Error getting seed from game tags for game of length %d: %v", l, err)
-				continue
+			ng, err := loadedGame(g, opts, strict)
+			if err != nil && strict {
+				log.Fatalf("Error generating game #%s loaded from storage: %v", g.Tags["#"], err)
 			}
-			ng, err := generateRandomGame(seed)
 			if err != nil {
-				log.Print(err)
+				log.Printf("Error reconstructing game of length %d: %v", l, err)
+				continue
 			}
 			ng.Tags = g.Tags
 			g = ng
 		}
-		sanMoves := getSANMoves(g)
+		r := gen.NewResult(g, l)
 		if g.Tags["sanMoves"] != "" {
-			genSanMoves := strings.Join(sanMoves, " ")
+			genSanMoves := strings.Join(r.SANMoves, " ")
 			if g.Tags["sanMoves"] != genSanMoves {
-				log.Print("Storage moves:   ", g.Tags["sanMoves"])
-				log.Print("Generated moves: ", genSanMoves)
+				logf(levelVerbose, "Storage moves:   %s", g.Tags["sanMoves"])
+				logf(levelVerbose, "Generated moves: %s", genSanMoves)
+				if strict {
+					log.Fatalf("Moves for game #%s loaded from storage are not equal to generated moves. Storage is stale, regenerate it.", g.Tags["#"])
+				}
 				log.Printf("Moves for game #%s loaded from storage are not equal to generated moves", g.Tags["#"])
 			}
 		}
-		log.Printf("Target length: %d | Random game #%s | half moves: %d", l, g.Tags["#"], len(g.Positions)-1)
-		if _, err := writer.WriteString(fmt.Sprintf("{\n\t\"Random-game-#%s_half-moves-%d_target-%d\", \"\",\n\t%#v,\n},\n", g.Tags["#"], len(g.Positions)-1, l, sanMoves)); err != nil {
-			log.Printf("Error writing result for length %d to result file: %v", l, err)
+		logf(levelNormal, "Target length: %d | Random game #%s | half moves: %d | status: %v%s", l, g.Tags["#"], len(g.Positions)-1, gen.GameStatus(g), statusLog(g))
+		results = append(results, r)
+	}
+	return results
+}
+
+// mirrorResults returns results with a result for the mirrored game after every result (see gen.MirrorGame).
+// Games, which can't be mirrored, are logged and have no mirrored result.
+func mirrorResults(results []gen.Result) []gen.Result {
+	mirrored := make([]gen.Result, 0, 2*len(results))
+	for _, r := range results {
+		mirrored = append(mirrored, r)
+		mg, err := gen.MirrorGame(r.Game)
+		if err != nil {
+			log.Printf("Error mirroring game #%d: %v", r.Seed, err)
+			continue
 		}
+		mirrored = append(mirrored, gen.NewResult(mg, r.Target))
 	}
+	return mirrored
 }
 
-func getGameLength(g *game.Game) int {
-	if len(g.Positions) == 0 { // Games with 0 length are from storage and the length is stored in capacity of Game.Positions slice.
-		return cap(g.Positions) - 1
+// loadedGame returns game loaded from storage with positions.
+// Games starting from the initial position are rehydrated from stored moves, other games, or all games if regenerate is true,
+// are generated again from their seed with opts.
+func loadedGame(g *game.Game, opts gen.Options, regenerate bool) (*game.Game, error) {
+	if opts.FEN == "" && !regenerate {
+		return gen.Rehydrate(strings.Fields(g.Tags["sanMoves"]))
 	}
-	return len(g.Positions) - 1
+	seed, ok := gen.GameSeed(g)
+	if !ok {
+		return nil, fmt.Errorf("can't get seed from game tags: %q", g.Tags["#"])
+	}
+	return gen.Generate(seed, opts)
 }
 
-func dist(a, b int) int {
-	if a > b {
-		return a - b
+// resultFileName returns name of the result file for the collector.
+// If suffix is not empty, it is appended with "_" before the extension, with "{date}" replaced by the current date.
+func resultFileName(out, collector string, searches int, suffix string) string {
+	name := fmt.Sprintf("./generated_%d.txt", searches)
+	if out != "" {
+		name = strings.ReplaceAll(out, "{name}", collector)
+	} else if collector != "" {
+		name = fmt.Sprintf("./generated_%s_%d.txt", collector, searches)
+	}
+	if suffix == "" {
+		return name
 	}
-	return b - a
+	suffix = strings.ReplaceAll(suffix, "{date}", time.Now().Format("2006-01-02"))
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "_" + suffix + ext
 }
 
-func addToGamesOfLength(g *game.Game) {
-	n := getGameLength(g)
-	for l, lg := range gamesOfLength {
-		if lg == nil {
-			gamesOfLength[l] = g
-			continue
+// writeResultFile writes results to the file.
+func writeResultFile(fileName string, rw resultWriter, results []gen.Result) error {
+	// Results are written to a temporary file, which replaces the result file on success,
+	// so the result file is never partially written nor contains content of a previous run.
+	tmpName := fileName + ".tmp"
+	f, err := os.Create(tmpName)
+	if err != nil {
+		return fmt.Errorf("creating result file: %v", err)
+	}
+	fail := func(format string, err error) error {
+		f.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf(format, err)
+	}
+	writer := bufio.NewWriter(f)
+	log.Printf("Writing results to: %s", fileName)
+	if err := rw.write(writer, results); err != nil {
+		return fail("writing results to result file: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fail("flushing result file: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fail("syncing result file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("closing result file: %v", err)
+	}
+	if err := os.Rename(tmpName, fileName); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("replacing result file: %v", err)
+	}
+	return nil
+}
+
+// appendResultFile appends the comment line and results to the file, which is created if it doesn't exist.
+func appendResultFile(fileName string, rw resultWriter, results []gen.Result, comment string) error {
+	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("opening result file: %v", err)
+	}
+	defer f.Close()
+	writer := bufio.NewWriter(f)
+	log.Printf("Appending results to: %s", fileName)
+	if err := rw.writeComment(writer, comment); err != nil {
+		return fmt.Errorf("writing comment to result file: %v", err)
+	}
+	if err := rw.write(writer, results); err != nil {
+		return fmt.Errorf("writing results to result file: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("flushing result file: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("syncing result file: %v", err)
+	}
+	return f.Close()
+}
+
+// seedRange returns range of seeds formatted for comments, e.g. "0-9999", or "none" if there are no seeds.
+func seedRange(seeds []int64) string {
+	if len(seeds) == 0 {
+		return "none"
+	}
+	min, max := seeds[0], seeds[0]
+	for _, s := range seeds {
+		if s < min {
+			min = s
 		}
-		lgn := getGameLength(lg)
-		if dist(l, n) < dist(l, lgn) {
-			gamesOfLength[l] = g
+		if s > max {
+			max = s
 		}
 	}
+	return fmt.Sprintf("%d-%d", min, max)
 }
 
-func generateRandomGame(seed int) (*game.Game, error) {
-	g, gs, err := game.New(), game.InProgress, error(nil)
-	g.Tags["#"] = fmt.Sprint(seed)
-	rnd := rand.New(rand.NewSource(int64(seed)))
-	for gs == game.InProgress {
-		movesMap := g.LegalMoves()
-		movesSlice := []move.Move{}
-		for key := range movesMap {
-			movesSlice = append(movesSlice, key)
-		}
-		sort.Slice(movesSlice, func(i, j int) bool {
-			return movesSlice[i].String() < movesSlice[j].String()
-		})
+// Verbosity levels of logs set by the -v flag.
+const (
+	levelQuiet = iota
+	levelNormal
+	levelVerbose
+)
+
+// Current verbosity level of logs.
+var verbosity = levelNormal
 
-		gs, err = g.MakeMove(movesSlice[rnd.Intn(len(movesSlice))])
+// logf logs the message, if verbosity is at least level. Errors are logged directly, regardless of verbosity.
+func logf(level int, format string, v ...interface{}) {
+	if verbosity >= level {
+		log.Printf(format, v...)
+	}
+}
+
+// Returns the collector name formatted for log lines, or empty string for the unnamed collector.
+func collectorLog(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" of collector %q", name)
+}
+
+// Returns draw reason or truncation of the game formatted for log lines, or empty string if there is nothing to add to the status.
+func statusLog(g *game.Game) string {
+	if gen.IsTruncated(g) {
+		return " (truncated)"
+	}
+	switch reason := gen.DrawReason(g); reason {
+	case "":
+	case gen.DrawFiftyMoveRule:
+		return fmt.Sprintf(" (%s, halfmove clock %d)", reason, gen.HalfmoveClock(g))
+	default:
+		return " (" + reason + ")"
+	}
+	return ""
+}
+
+// parseSeeds parses comma separated list of seeds. Empty list returns nil.
+func parseSeeds(list string) ([]int64, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	seeds := []int64{}
+	for _, s := range strings.Split(list, ",") {
+		seed, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
 		if err != nil {
 			return nil, err
 		}
+		seeds = append(seeds, seed)
+	}
+	return seeds, nil
+}
+
+// Tie break policies of collectors by values of the -tie-break flag.
+var tieBreaks = map[string]gen.TieBreak{
+	"seed":     gen.TieBreakSeed,
+	"shorter":  gen.TieBreakShorter,
+	"longer":   gen.TieBreakLonger,
+	"captures": gen.TieBreakCaptures,
+}
+
+// parseShard parses shard in the form "k/n", where 1 <= k <= n, and returns k and n.
+func parseShard(s string) (int, int, error) {
+	ks, ns, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected \"k/n\", got %q", s)
+	}
+	k, err := strconv.Atoi(ks)
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := strconv.Atoi(ns)
+	if err != nil {
+		return 0, 0, err
 	}
-	return g, nil
+	if n < 1 || k < 1 || k > n {
+		return 0, 0, fmt.Errorf("shard has to be from 1 to number of shards, which has to be positive, got %d/%d", k, n)
+	}
+	return k, n, nil
+}
+
+// inShard reports whether the seed belongs to the 1-based shard of shards, i.e. seed % shards == shard-1.
+func inShard(seed int64, shard, shards int) bool {
+	r := seed % int64(shards)
+	if r < 0 {
+		r += int64(shards)
+	}
+	return r == int64(shard-1)
 }
 
-func getSANMoves(g *game.Game) []string {
-	sanMoves := make([]string, 0, len(g.Positions)-1)
-	for i := range g.Positions {
-		if g.Positions[i].LastMove != move.Null {
-			sanMoves = append(sanMoves, g.Positions[i-1].SAN(g.Positions[i].LastMove))
+// parsePicker returns the move picker with the name, or nil for the default uniform picker.
+func parsePicker(name string) (gen.Picker, error) {
+	switch name {
+	case "uniform":
+		return nil, nil
+	case "captures":
+		return gen.CapturePicker(3), nil
+	case "central":
+		return gen.CentralBiasPicker(3), nil
+	case "first":
+		return gen.FirstSANPicker, nil
+	}
+	return nil, fmt.Errorf("unknown move picker \"%s\"", name)
+}
+
+// storageOptions describes options of the generation changing moves of games, which are recorded in storage, so games generated with other options are not loaded from it.
+// Options with default values and side pickers equal to picker are left out, so the description is empty for default options.
+func storageOptions(picker, whitePicker, blackPicker string, noEarlyQueen int, avoidRepetition, stopInsufficient, stopDead bool) string {
+	parts := []string{}
+	if picker != "uniform" {
+		parts = append(parts, "picker="+picker)
+	}
+	if whitePicker != "" && whitePicker != picker {
+		parts = append(parts, "white-picker="+whitePicker)
+	}
+	if blackPicker != "" && blackPicker != picker {
+		parts = append(parts, "black-picker="+blackPicker)
+	}
+	if noEarlyQueen > 0 {
+		parts = append(parts, fmt.Sprint("no-early-queen=", noEarlyQueen))
+	}
+	for _, o := range []struct {
+		name string
+		set  bool
+	}{{"avoid-repetition", avoidRepetition}, {"stop-insufficient", stopInsufficient}, {"stop-dead", stopDead}} {
+		if o.set {
+			parts = append(parts, o.name)
 		}
 	}
-	return sanMoves
+	return strings.Join(parts, " ")
 }
 
-func storeGame(writer *bufio.Writer, g *game.Game) {
-	_, err := writer.WriteString(fmt.Sprint(len(g.Positions)-1, " ", strings.Join(getSANMoves(g), " "), "\n"))
-	if err != nil {
-		log.Printf("Error storing game to storage: %v", err)
+// readSeeds reads seeds from r, one per line. Blank lines and comments starting with "#" are skipped.
+// The returned slice is not nil, even if there are no seeds.
+func readSeeds(r io.Reader) ([]int64, error) {
+	seeds := []int64{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line += 1
+		s, _, _ := strings.Cut(scanner.Text(), "#")
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		seed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		seeds = append(seeds, seed)
+	}
+	return seeds, scanner.Err()
+}
+
+// namedTargets holds values of repeated -collector flags in the form "name=targets".
+type namedTargets []struct{ name, targets string }
+
+func (nt *namedTargets) String() string {
+	parts := []string{}
+	for _, t := range *nt {
+		parts = append(parts, t.name+"="+t.targets)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (nt *namedTargets) Set(value string) error {
+	name, targets, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected \"name=targets\", got %q", value)
 	}
-	err = writer.Flush()
+	*nt = append(*nt, struct{ name, targets string }{name, targets})
+	return nil
+}
+
+// parseTargets parses comma separated list of targets to a collector.
+// Targets prefixed with "=" are exact, targets "longest", "shortest" and "longest-mate" keep the longest game, the shortest decisive game and the longest mate.
+// Targets prefixed with "p" (e.g. "p25") are percentiles of lengths of all games.
+func parseTargets(list string) (*gen.LengthCollector, error) {
+	targets := []int{}
+	exact := []int{}
+	percentiles := []int{}
+	longest, shortest, longestMate := false, false, false
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		switch s {
+		case "longest":
+			longest = true
+			continue
+		case "shortest":
+			shortest = true
+			continue
+		case "longest-mate":
+			longestMate = true
+			continue
+		}
+		if strings.HasPrefix(s, "p") {
+			p, err := strconv.Atoi(strings.TrimPrefix(s, "p"))
+			if err != nil {
+				return nil, err
+			}
+			if p <= 0 || p > 100 {
+				return nil, fmt.Errorf("percentile has to be from 1 to 100, got %d", p)
+			}
+			percentiles = append(percentiles, p)
+			continue
+		}
+		isExact := strings.HasPrefix(s, "=")
+		t, err := strconv.Atoi(strings.TrimPrefix(s, "="))
+		if err != nil {
+			return nil, err
+		}
+		if t < 0 {
+			return nil, fmt.Errorf("target can't be negative, got %d", t)
+		}
+		targets = append(targets, t)
+		if isExact {
+			exact = append(exact, t)
+		}
+	}
+	c := gen.NewLengthCollector(targets)
+	for _, t := range exact {
+		c.SetExact(t, true)
+	}
+	if len(percentiles) > 0 {
+		c.SetPercentiles(percentiles)
+	}
+	c.SetLongest(longest)
+	c.SetShortest(shortest)
+	c.SetLongestMate(longestMate)
+	return c, nil
+}
+
+// writeReport writes report to the file, or to stderr if name is "-".
+func writeReport(name string, report func(io.Writer) error) error {
+	if name == "-" {
+		return report(os.Stderr)
+	}
+	f, err := os.Create(name)
 	if err != nil {
-		log.Printf("Error flushing game to storage writer: %v", err)
+		return err
+	}
+	if err := report(f); err != nil {
+		f.Close()
+		return err
 	}
-	addToGamesOfLength(g)
+	return f.Close()
 }
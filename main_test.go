@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andrewbackes/chess/game"
+	"github.com/jezek/chess-game-generator/gen"
+)
+
+func TestStorageOptions(t *testing.T) {
+	for _, c := range []struct {
+		picker, whitePicker, blackPicker string
+		noEarlyQueen                     int
+		avoidRepetition, stopDead        bool
+		want                             string
+	}{
+		{"uniform", "", "", 0, false, false, ""},
+		{"uniform", "uniform", "uniform", 0, false, false, ""},
+		{"captures", "", "captures", 0, false, false, "picker=captures"},
+		{"uniform", "first", "", 4, true, true, "white-picker=first no-early-queen=4 avoid-repetition stop-dead"},
+	} {
+		if got := storageOptions(c.picker, c.whitePicker, c.blackPicker, c.noEarlyQueen, c.avoidRepetition, false, c.stopDead); got != c.want {
+			t.Errorf("storage options of %+v are %q, want %q", c, got, c.want)
+		}
+	}
+}
+
+func TestBothCastledStored(t *testing.T) {
+	const fen = "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R b KQkq - 0 1"
+	for _, c := range []struct {
+		fen, moves string
+		want       bool
+	}{
+		{"", "e4 e5 Nf3 Nf6 Bc4 Bc5 O-O O-O", true},
+		{"", "e4 e5 Nf3 Nf6 Bc4 Bc5 O-O d6 d3 O-O", true},
+		{"", "e4 e5 Nf3 Nf6 Bc4 Bc5 O-O d6", false},
+		{fen, "O-O-O O-O", true},
+		{fen, "O-O a3", false},
+	} {
+		g := &game.Game{Tags: map[string]string{"sanMoves": c.moves}}
+		if c.fen != "" {
+			g.Tags[gen.TagFEN] = c.fen
+		}
+		if got := bothCastled(g); got != c.want {
+			t.Errorf("both sides castled in stored game %q from %q: %v, want %v", c.moves, c.fen, got, c.want)
+		}
+		// Stored games agree with replayed games, which know the side of every move.
+		replayed, err := gen.RehydrateFrom(c.fen, strings.Fields(c.moves))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bothCastled(replayed); got != c.want {
+			t.Errorf("both sides castled in replayed game %q from %q: %v, want %v", c.moves, c.fen, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/jezek/chess-game-generator/storage"
+)
+
+// runMerge runs the merge subcommand, which merges storage files into a new storage file and reports the number of games taken from each of them.
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	policy := fs.String("policy", storage.MergeLonger, "Policy for games with the same seed in more files: \""+storage.MergeLonger+"\" keeps the longer game, \""+storage.MergeFirst+"\" the game from the earliest file, \""+storage.MergeLast+"\" the game from the latest file.")
+	compress := fs.Bool("compress", false, "Write gzip compressed merged storage.")
+	format := fs.String("storage-format", "text", "Format of merged storage: \"text\" or \"jsonl\".")
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		log.Fatalf("Subcommand merge expects output storage file and at least one input storage file, got %d arguments", fs.NArg())
+	}
+	if *format != "text" && *format != "jsonl" {
+		log.Fatalf("Unknown storage format \"%s\"", *format)
+	}
+	out, in := fs.Arg(0), fs.Args()[1:]
+	counts, err := storage.Merge(out, in, *policy, *compress, *format == "jsonl")
+	if err != nil {
+		log.Fatalf("Error merging storage files: %v", err)
+	}
+	total := 0
+	for i, name := range in {
+		fmt.Printf("%s: %d games\n", name, counts[i])
+		total += counts[i]
+	}
+	fmt.Printf("Merged %d games to %s\n", total, out)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jezek/chess-game-generator/gen"
+)
+
+// Targets of collectors comparing sequential and parallel generation.
+var parallelTargets = []int{10, 25, 50, 100, 250, 500}
+
+// sequentialCollector generates games for seeds one by one with gen.Generate and collects them for parallelTargets.
+func sequentialCollector(t *testing.T, seeds []int64) *gen.LengthCollector {
+	t.Helper()
+	c := gen.NewLengthCollector(parallelTargets)
+	for _, seed := range seeds {
+		g, err := gen.Generate(seed, gen.Options{})
+		if err != nil {
+			t.Fatalf("generating game with seed #%d: %v", seed, err)
+		}
+		c.Add(g)
+	}
+	return c
+}
+
+// parallelCollector generates games for seeds in workers goroutines and collects them for parallelTargets.
+func parallelCollector(t *testing.T, seeds []int64, workers int) *gen.LengthCollector {
+	t.Helper()
+	c := gen.NewLengthCollector(parallelTargets)
+	err := gen.GenerateParallel(context.Background(), seeds, workers, gen.Options{}, func(r gen.GameResult) error {
+		if r.Err != nil {
+			return r.Err
+		}
+		c.Add(r.Game)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("generating games with %d workers: %v", workers, err)
+	}
+	return c
+}
+
+// resultFile writes results selected by the collector in the format to a result file in dir and returns its content.
+func resultFile(t *testing.T, dir string, c *gen.LengthCollector, format string) []byte {
+	t.Helper()
+	idTmpl, err := parseIDTemplate(defaultIDTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "generated."+format)
+	if err := writeResultFile(name, resultWriter{format: format, idTemplate: idTmpl}, selectResults(c, gen.Options{}, false)); err != nil {
+		t.Fatalf("writing results in format %s: %v", format, err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestGenerateParallelResultFilesEqualSequential(t *testing.T) {
+	seeds := make([]int64, 200)
+	for i := range seeds {
+		seeds[i] = int64(i)
+	}
+	formats := []string{"go", "pgn", "json", "uci", "epd", "fens", "csv", "figurine"}
+	sequential := sequentialCollector(t, seeds)
+	want := map[string][]byte{}
+	for _, format := range formats {
+		if !validFormat(format) {
+			t.Fatalf("format %s is not valid", format)
+		}
+		want[format] = resultFile(t, t.TempDir(), sequential, format)
+	}
+	for _, workers := range []int{1, 2, 8} {
+		c := parallelCollector(t, seeds, workers)
+		dir := t.TempDir()
+		for _, format := range formats {
+			if got := resultFile(t, dir, c, format); !bytes.Equal(got, want[format]) {
+				t.Errorf("result file in format %s with %d workers differs from sequential generation\n got: %s\nwant: %s", format, workers, got, want[format])
+			}
+		}
+	}
+}
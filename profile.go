@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// seedProfile is the generation time of the game with a seed.
+type seedProfile struct {
+	seed      int64
+	halfMoves int
+	duration  time.Duration
+}
+
+// seedProfiles keeps generation times of games to report the slowest seeds.
+type seedProfiles []seedProfile
+
+// add records the generation time of the game with seed.
+func (ps *seedProfiles) add(seed int64, halfMoves int, duration time.Duration) {
+	*ps = append(*ps, seedProfile{seed, halfMoves, duration})
+}
+
+// report logs the slowest n seeds with length of their games, duration and duration per half-move.
+// Long duration per half-move means positions with many legal moves rather than a long game.
+func (ps seedProfiles) report(n int) {
+	sort.SliceStable(ps, func(i, j int) bool {
+		return ps[i].duration > ps[j].duration
+	})
+	if n > len(ps) {
+		n = len(ps)
+	}
+	log.Printf("Slowest %d of %d generated games:", n, len(ps))
+	for _, p := range ps[:n] {
+		perHalfMove := time.Duration(0)
+		if p.halfMoves > 0 {
+			perHalfMove = p.duration / time.Duration(p.halfMoves)
+		}
+		log.Printf("Seed #%d | half-moves: %d | duration: %v | per half-move: %v", p.seed, p.halfMoves, p.duration, perHalfMove)
+	}
+}
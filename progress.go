@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Outcomes of finished seeds counted by progress.
+const (
+	outcomeGenerated = "generated"
+	// Generation abandoned by -bail-below, -adaptive-bail or -max-material-swing.
+	outcomeAbandoned = "abandoned"
+	outcomeFailed    = "failed"
+)
+
+// progress periodically logs the number of finished seeds with their outcomes, generation speed and estimated time of arrival.
+// Every seed delivered by generation is counted, whether its game was generated, abandoned or failed.
+// If deadline is set, the total is unknown and the time left until deadline is logged instead.
+type progress struct {
+	total, every, done int
+	// Number of finished seeds for every outcome other than outcomeGenerated.
+	outcomes map[string]int
+	start    time.Time
+	deadline time.Time
+}
+
+func newProgress(total, every int) *progress {
+	return &progress{
+		total:    total,
+		every:    every,
+		outcomes: map[string]int{},
+		start:    time.Now(),
+	}
+}
+
+// add counts one finished seed with the outcome and logs progress every p.every seeds and after the last one.
+// Nil progress, when progress is turned off, counts nothing.
+func (p *progress) add(outcome string) {
+	if p == nil {
+		return
+	}
+	p.done += 1
+	if outcome != outcomeGenerated {
+		p.outcomes[outcome] += 1
+	}
+	if p.done%p.every != 0 && p.done != p.total {
+		return
+	}
+	elapsed := time.Since(p.start)
+	rate := float64(p.done) / elapsed.Seconds()
+	if !p.deadline.IsZero() {
+		if p.done%p.every == 0 {
+			logf(levelNormal, "Finished %d seeds%s | %.1f seeds/sec | time left %v", p.done, p.outcomesLog(), rate, time.Until(p.deadline).Round(time.Second))
+		}
+		return
+	}
+	eta := time.Duration(float64(p.total-p.done) / rate * float64(time.Second))
+	logf(levelNormal, "Finished %d/%d seeds%s | %.1f seeds/sec | ETA %v", p.done, p.total, p.outcomesLog(), rate, eta.Round(time.Second))
+}
+
+// outcomesLog returns the number of seeds finished with outcomes other than outcomeGenerated formatted for log lines, or empty string if all games were generated.
+func (p *progress) outcomesLog() string {
+	parts := []string{}
+	for _, outcome := range []string{outcomeAbandoned, outcomeFailed} {
+		if n := p.outcomes[outcome]; n > 0 {
+			parts = append(parts, fmt.Sprint(n, " ", outcome))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d %s, %s)", p.done-p.outcomes[outcomeAbandoned]-p.outcomes[outcomeFailed], outcomeGenerated, strings.Join(parts, ", "))
+}
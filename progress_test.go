@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestProgressCountsEveryFinishedSeed(t *testing.T) {
+	buf := bytes.Buffer{}
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+	p := newProgress(5, 2)
+	for _, outcome := range []string{outcomeGenerated, outcomeAbandoned, outcomeFailed, outcomeGenerated, outcomeAbandoned} {
+		p.add(outcome)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"Finished 2/5 seeds (1 generated, 1 abandoned)",
+		"Finished 4/5 seeds (2 generated, 1 abandoned, 1 failed)",
+		"Finished 5/5 seeds (2 generated, 2 abandoned, 1 failed)",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("logged progress lines %q, want %d lines", lines, len(want))
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, want[i]) {
+			t.Errorf("progress line %d is %q, want it to contain %q", i+1, line, want[i])
+		}
+	}
+	var off *progress
+	off.add(outcomeGenerated)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/andrewbackes/chess/game"
+	"github.com/jezek/chess-game-generator/gen"
+)
+
+// Description of result file formats for the -format flag.
+const formatsUsage = `"go" for Go literals of SAN moves, final FEN and draw reason, "pgn" for PGN games, "json" for JSON array of results, "uci" for lines with identifier and UCI moves, "epd" for EPD records of final positions, "fens" for FENs of all positions of games separated by empty lines, "csv" for CSV summary of selected games, "figurine" for lines with identifier and SAN moves with Unicode piece figurines.`
+
+// Default template of result identifiers in Go literal, UCI and figurine formats.
+const defaultIDTemplate = "Random-game-#{{.Seed}}_half-moves-{{.HalfMoves}}_target-{{.Target}}"
+
+func validFormat(format string) bool {
+	switch format {
+	case "go", "pgn", "json", "uci", "epd", "fens", "csv", "figurine":
+		return true
+	}
+	return false
+}
+
+// Prefixes of comment lines in result file formats, which can be appended to by the -append-results flag.
+// PGN uses escape lines, which are ignored by PGN readers.
+var commentPrefixes = map[string]string{
+	"go":  "// ",
+	"pgn": "% ",
+}
+
+// resultWriter writes selected games to the result file.
+type resultWriter struct {
+	format string
+	// Template of result identifiers in Go literal, UCI and figurine formats, executed with gen.Result.
+	idTemplate *template.Template
+	// Options of results in PGN format.
+	pgn gen.PGNOptions
+}
+
+// parseIDTemplate parses result identifier template and checks it by executing it on a sample result.
+func parseIDTemplate(text string) (*template.Template, error) {
+	t, err := template.New("id").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Execute(&strings.Builder{}, gen.Result{Seed: 1, HalfMoves: 10, Target: 10}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// write writes results to writer.
+func (rw resultWriter) write(writer *bufio.Writer, results []gen.Result) error {
+	switch rw.format {
+	case "json":
+		return gen.WriteResultsJSON(writer, results)
+	case "pgn":
+		games := make([]*game.Game, 0, len(results))
+		for _, r := range results {
+			games = append(games, targetGame(r))
+		}
+		return rw.pgn.WritePGNDatabase(writer, games)
+	case "csv":
+		return writeResultsCSV(writer, results)
+	}
+	for _, r := range results {
+		if err := rw.writeResult(writer, r); err != nil {
+			return fmt.Errorf("writing result for length %d: %v", r.Target, err)
+		}
+	}
+	return nil
+}
+
+// writeComment writes a comment line with text to writer. The format has to have a comment prefix (see commentPrefixes).
+func (rw resultWriter) writeComment(writer *bufio.Writer, text string) error {
+	prefix, ok := commentPrefixes[rw.format]
+	if !ok {
+		return fmt.Errorf("format \"%s\" has no comments", rw.format)
+	}
+	_, err := writer.WriteString(prefix + text + "\n")
+	return err
+}
+
+// targetGame returns a copy of the game of the result with the target in the gen.TagTarget tag.
+func targetGame(r gen.Result) *game.Game {
+	g := *r.Game
+	g.Tags = make(map[string]string, len(r.Game.Tags)+1)
+	for k, v := range r.Game.Tags {
+		g.Tags[k] = v
+	}
+	g.Tags[gen.TagTarget] = fmt.Sprint(r.Target)
+	return &g
+}
+
+// writeSplitResults writes every result as a PGN game to its own file "target-<N>_seed-<S>.pgn" in the directory, which is created if needed.
+// Files of mirrored games have "_mirrored" suffix.
+func writeSplitResults(dir string, results []gen.Result, opts gen.PGNOptions) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, r := range results {
+		name := fmt.Sprintf("target-%d_seed-%d", r.Target, r.Seed)
+		if r.Mirrored {
+			name += "_mirrored"
+		}
+		name = filepath.Join(dir, name+".pgn")
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		if err := opts.WritePGN(f, targetGame(r)); err != nil {
+			f.Close()
+			return fmt.Errorf("writing \"%s\": %v", name, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rw resultWriter) writeResult(writer *bufio.Writer, r gen.Result) error {
+	g := r.Game
+	switch rw.format {
+	case "fens":
+		if err := gen.WriteFENs(writer, g); err != nil {
+			return err
+		}
+		_, err := writer.WriteString("\n")
+		return err
+	case "epd":
+		epd := gen.FinalEPD(g)
+		if epd == "" {
+			return fmt.Errorf("can't encode final position")
+		}
+		_, err := writer.WriteString(epd + "\n")
+		return err
+	case "uci":
+		id, err := rw.id(r)
+		if err != nil {
+			return err
+		}
+		_, err = writer.WriteString(id + " " + strings.Join(gen.UCIMoves(g), " ") + "\n")
+		return err
+	case "figurine":
+		id, err := rw.id(r)
+		if err != nil {
+			return err
+		}
+		_, err = writer.WriteString(id + " " + strings.Join(gen.FigurineSAN(g), " ") + "\n")
+		return err
+	default:
+		id, err := rw.id(r)
+		if err != nil {
+			return err
+		}
+		_, err = writer.WriteString(fmt.Sprintf("{\n\t%q, \"\",\n\t%#v,\n\t%q, %q,\n},\n", id, r.SANMoves, gen.FinalFEN(g), gen.DrawReason(g)))
+		return err
+	}
+}
+
+// id returns identifier of the result. Identifiers of mirrored games have "_mirrored" suffix.
+func (rw resultWriter) id(r gen.Result) (string, error) {
+	id := strings.Builder{}
+	if err := rw.idTemplate.Execute(&id, r); err != nil {
+		return "", err
+	}
+	if r.Mirrored {
+		id.WriteString("_mirrored")
+	}
+	return id.String(), nil
+}
+
+// Header of results in CSV format.
+var csvHeader = []string{"seed", "target", "halfMoves", "distance", "result", "captures", "checks", "eco", "finalFEN"}
+
+// writeResultsCSV writes results to w in CSV format with csvHeader.
+func writeResultsCSV(w io.Writer, results []gen.Result) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, r := range results {
+		distance := r.HalfMoves - r.Target
+		if distance < 0 {
+			distance = -distance
+		}
+		captures, checks := gen.GameCounts(r.Game)
+		cw.Write([]string{
+			strconv.FormatInt(r.Seed, 10),
+			strconv.Itoa(r.Target),
+			strconv.Itoa(r.HalfMoves),
+			strconv.Itoa(distance),
+			r.Result,
+			strconv.Itoa(captures),
+			strconv.Itoa(checks),
+			r.ECO,
+			gen.FinalFEN(r.Game),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
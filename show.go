@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/andrewbackes/chess/fen"
+	"github.com/andrewbackes/chess/piece"
+	"github.com/jezek/chess-game-generator/gen"
+	"github.com/jezek/chess-game-generator/storage"
+)
+
+// runShow runs the show subcommand, which replays the game with the seed from the storage file
+// and prints board, SAN move and FEN after every move and the final status of the game.
+func runShow(storageFileName string, args []string) {
+	if len(args) != 1 {
+		log.Fatalf("Subcommand show expects a seed, got %d arguments", len(args))
+	}
+	seed, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		log.Fatalf("Error parsing seed: %v", err)
+	}
+	games, err := storage.ReadFile(storageFileName)
+	if err != nil {
+		log.Fatalf("Error reading storage file \"%s\": %v", storageFileName, err)
+	}
+	sg, ok := games[seed]
+	if !ok {
+		log.Fatalf("No game with seed #%d in storage file \"%s\"", seed, storageFileName)
+	}
+	g, err := gen.RehydrateFrom(sg.FEN, sg.Moves)
+	if err != nil {
+		log.Fatalf("Error replaying game with seed #%d: %v", seed, err)
+	}
+	w := os.Stdout
+	fmt.Fprintf(w, "Random game #%d\n\n", seed)
+	gen.PrintBoard(w, g.Positions[0])
+	for i, san := range sg.Moves {
+		prev, pos := g.Positions[i], g.Positions[i+1]
+		if prev.ActiveColor == piece.White {
+			fmt.Fprintf(w, "\n%d. %s\n", prev.MoveNumber, san)
+		} else {
+			fmt.Fprintf(w, "\n%d... %s\n", prev.MoveNumber, san)
+		}
+		gen.PrintBoard(w, pos)
+		s, err := fen.Encode(pos)
+		if err != nil {
+			log.Fatalf("Error encoding position after move %d: %v", i+1, err)
+		}
+		fmt.Fprintln(w, s)
+	}
+	fmt.Fprintf(w, "\nStatus after %d half-moves: %v%s\n", len(sg.Moves), gen.GameStatus(g), statusLog(g))
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Differences holds seeds of games, which are only in the second storage (Added), only in the first storage (Deleted),
+// or in both storages with different moves, starting positions or generation options (Modified). All seeds are sorted.
+type Differences struct {
+	Added, Deleted, Modified []int64
+}
+
+// Diff compares games in storage files a and b, which can be in any supported format, e.g. old storage to a new one without migrating it.
+func Diff(a, b string) (Differences, error) {
+	gamesA, err := ReadFile(a)
+	if err != nil {
+		return Differences{}, fmt.Errorf("reading storage file \"%s\": %v", a, err)
+	}
+	gamesB, err := ReadFile(b)
+	if err != nil {
+		return Differences{}, fmt.Errorf("reading storage file \"%s\": %v", b, err)
+	}
+	d := Differences{}
+	for seed, sgA := range gamesA {
+		sgB, ok := gamesB[seed]
+		if !ok {
+			d.Deleted = append(d.Deleted, seed)
+		} else if sgA.FEN != sgB.FEN || sgA.Options != sgB.Options || strings.Join(sgA.Moves, " ") != strings.Join(sgB.Moves, " ") {
+			d.Modified = append(d.Modified, seed)
+		}
+	}
+	for seed := range gamesB {
+		if _, ok := gamesA[seed]; !ok {
+			d.Added = append(d.Added, seed)
+		}
+	}
+	sortSeeds(d.Added)
+	sortSeeds(d.Deleted)
+	sortSeeds(d.Modified)
+	return d, nil
+}
+
+// DiffStorage returns sorted seeds of games, which differ between storage files a and b.
+// A game differs if it is stored only in one of the files, or its moves, starting position or generation options are not the same in both files.
+// Storage files can be in any supported format, e.g. old storage can be compared to a new one without migrating it.
+func DiffStorage(a, b string) ([]int64, error) {
+	d, err := Diff(a, b)
+	if err != nil {
+		return nil, err
+	}
+	seeds := append(append(append([]int64{}, d.Added...), d.Deleted...), d.Modified...)
+	sortSeeds(seeds)
+	return seeds, nil
+}
+
+// sortSeeds sorts seeds in increasing order.
+func sortSeeds(seeds []int64) {
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i] < seeds[j] })
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffStorage(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt.gz")
+	// Seeds over the range of 32-bit int are kept.
+	big := int64(1) << 40
+	writeTestStorage(t, a, testGame(0, 1), testGame(1, 2), testGame(2, 3), testGame(big, 1))
+	if err := WriteFile(b, "", "", []Game{testGame(0, 1), testGame(2, 4), testGame(3, 1), testGame(big, 2)}, true, true); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Differences{Added: []int64{3}, Deleted: []int64{1}, Modified: []int64{2, big}}); !reflect.DeepEqual(d, want) {
+		t.Errorf("differences are %+v, want %+v", d, want)
+	}
+	seeds, err := DiffStorage(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{1, 2, 3, big}; !reflect.DeepEqual(seeds, want) {
+		t.Errorf("differing seeds are %v, want %v", seeds, want)
+	}
+	if seeds, err := DiffStorage(a, a); err != nil || len(seeds) != 0 {
+		t.Errorf("differing seeds of the same file are %v, %v, want none", seeds, err)
+	}
+}
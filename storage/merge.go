@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Policies resolving games with the same seed in more merged storage files.
+const (
+	// MergeLonger keeps the game with more half-moves, or the game from the earlier file if they are equally long.
+	MergeLonger = "longer"
+	// MergeFirst keeps the game from the earliest file.
+	MergeFirst = "first"
+	// MergeLast keeps the game from the latest file.
+	MergeLast = "last"
+)
+
+// MergeStorage merges storage files in into a new storage file out, with games sorted by seed, and logs the number of games taken from each input file.
+// Games with the same seed in more files are resolved by keeping the longer game.
+// Input files can be in any supported format, out is written as uncompressed text storage.
+func MergeStorage(out string, in ...string) error {
+	counts, err := Merge(out, in, MergeLonger, false, false)
+	if err != nil {
+		return err
+	}
+	for i, name := range in {
+		log.Printf("Merged %d games from storage file \"%s\" to \"%s\"", counts[i], name, out)
+	}
+	return nil
+}
+
+// Merge merges storage files in into out, resolving games with the same seed by the policy, and returns the number of merged games taken from each input file.
+// All games have to start from the same position and be generated with the same options, which are kept in the merged storage.
+func Merge(out string, in []string, policy string, compress, jsonl bool) ([]int, error) {
+	switch policy {
+	case MergeLonger, MergeFirst, MergeLast:
+	default:
+		return nil, fmt.Errorf("unknown merge policy %q", policy)
+	}
+	merged := map[int64]Game{}
+	source := map[int64]int{}
+	startFEN, options, startName := "", "", ""
+	for i, name := range in {
+		games, err := ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("reading storage file \"%s\": %v", name, err)
+		}
+		for seed, sg := range games {
+			if startName == "" {
+				startFEN, options, startName = sg.FEN, sg.Options, name
+			} else if sg.FEN != startFEN {
+				return nil, fmt.Errorf("storage file \"%s\" holds games starting from %s, but \"%s\" from %s", name, StartDescription(sg.FEN), startName, StartDescription(startFEN))
+			} else if sg.Options != options {
+				return nil, fmt.Errorf("storage file \"%s\" holds games generated with %s, but \"%s\" with %s", name, OptionsDescription(sg.Options), startName, OptionsDescription(options))
+			}
+			old, ok := merged[seed]
+			keep := ok && (policy == MergeFirst || policy == MergeLonger && len(sg.Moves) <= len(old.Moves))
+			if ok && strings.Join(old.Moves, " ") != strings.Join(sg.Moves, " ") {
+				kept := name
+				if keep {
+					kept = in[source[seed]]
+				}
+				log.Printf("Game #%d differs in storage files \"%s\" and \"%s\", keeping game from \"%s\"", seed, in[source[seed]], name, kept)
+			}
+			if keep {
+				continue
+			}
+			merged[seed] = sg
+			source[seed] = i
+		}
+	}
+	seeds := make([]int64, 0, len(merged))
+	for seed := range merged {
+		seeds = append(seeds, seed)
+	}
+	sortSeeds(seeds)
+	games := make([]Game, 0, len(seeds))
+	counts := make([]int, len(in))
+	for _, seed := range seeds {
+		sg := merged[seed]
+		// Games from storage without counts are counted, so the merged storage doesn't have to be migrated.
+		if err := sg.count(); err != nil {
+			return nil, fmt.Errorf("counting captures and checks of game #%d: %v", seed, err)
+		}
+		games = append(games, sg)
+		counts[source[seed]] += 1
+	}
+	if err := WriteFile(out, startFEN, options, games, compress, jsonl); err != nil {
+		return nil, fmt.Errorf("writing storage file \"%s\": %v", out, err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeStorage(t *testing.T) {
+	dir := t.TempDir()
+	a, b, out := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "out.txt")
+	writeTestStorage(t, a, testGame(3, 2), testGame(1, 5))
+	writeTestStorage(t, b, testGame(2, 1), testGame(1, 7), testGame(3, 1))
+	if err := MergeStorage(out, a, b); err != nil {
+		t.Fatal(err)
+	}
+	// Collisions keep the longer game.
+	writeTestStorage(t, filepath.Join(dir, "want.txt"), testGame(1, 7), testGame(2, 1), testGame(3, 2))
+	gotLines, wantLines := readLines(t, out), readLines(t, filepath.Join(dir, "want.txt"))
+	if !reflect.DeepEqual(gotLines, wantLines) {
+		t.Errorf("merged storage is\n%s\nwant sorted by seed\n%s", strings.Join(gotLines, "\n"), strings.Join(wantLines, "\n"))
+	}
+}
+
+func TestMergePolicies(t *testing.T) {
+	dir := t.TempDir()
+	a, b, out := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "out.txt")
+	writeTestStorage(t, a, testGame(0, 5), testGame(1, 1))
+	writeTestStorage(t, b, testGame(0, 3), testGame(1, 4), testGame(2, 1))
+	for _, c := range []struct {
+		policy string
+		// Half-moves of merged games with seeds 0 and 1 and the number of games taken from a and b.
+		n      [2]int
+		counts []int
+	}{
+		{MergeLonger, [2]int{5, 4}, []int{1, 2}},
+		{MergeFirst, [2]int{5, 1}, []int{2, 1}},
+		{MergeLast, [2]int{3, 4}, []int{0, 3}},
+	} {
+		counts, err := Merge(out, []string{a, b}, c.policy, false, false)
+		if err != nil {
+			t.Errorf("merging with policy %q: %v", c.policy, err)
+			continue
+		}
+		if !reflect.DeepEqual(counts, c.counts) {
+			t.Errorf("merging with policy %q took %v games from input files, want %v", c.policy, counts, c.counts)
+		}
+		games, err := ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := [2]int{len(games[0].Moves), len(games[1].Moves)}; got != c.n {
+			t.Errorf("merging with policy %q kept games with %v half-moves, want %v", c.policy, got, c.n)
+		}
+	}
+	if _, err := Merge(out, []string{a, b}, "shortest", false, false); err == nil {
+		t.Errorf("merging with unknown policy: expected error")
+	}
+	fen := filepath.Join(dir, "fen.txt")
+	if err := WriteFile(fen, "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", "", []Game{testGame(5, 1)}, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Merge(out, []string{a, fen}, MergeLonger, false, false); err == nil {
+		t.Errorf("merging storage files with games from different starting positions: expected error")
+	}
+}
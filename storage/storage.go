@@ -0,0 +1,636 @@
+// Package storage keeps generated games in files, so they don't have to be generated again.
+package storage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrewbackes/chess/game"
+	"github.com/andrewbackes/chess/position"
+	"github.com/jezek/chess-game-generator/gen"
+)
+
+// Prefix of the first line of storage files, followed by the storage format version.
+const HeaderPrefix = "#chess-game-generator storage v"
+
+// Current storage format version. Version 2 has the seed on every line, version 3 adds the number of captures and checks,
+// version 4 adds an optional second header line with FEN of the starting position of all stored games,
+// version 5 adds an optional header line with options of the generation of all stored games.
+const Version = 5
+
+// Prefix of the second header line of storage files with games not starting from the initial position, followed by FEN of their starting position.
+const FENPrefix = "#fen "
+
+// Prefix of the header line of storage files with games not generated with default options, following the FEN line if there is one.
+// It is followed by the options, as set in Storage.Options.
+const OptionsPrefix = "#options "
+
+// Header is the first line of storage files in the current format.
+var Header = fmt.Sprint(HeaderPrefix, Version)
+
+// Number of games stored in compressed storage, after which the compressed file is rewritten.
+const compressedSaveInterval = 100
+
+// Storage keeps generated games in a file, one game per line, so they don't have to be generated again.
+// The file starts with Header and each line contains the seed of the game, the number of half-moves,
+// the number of captures, the number of checks and SAN moves.
+// Games not starting from the initial position are stored in files with a second header line starting with FENPrefix.
+// Games generated with other than default options are stored in files with a header line starting with OptionsPrefix.
+//
+// Files written by older versions have no header and the line number (counted from 0) is the seed of the game,
+// or a header of version 2 without the number of captures and checks. Such files are migrated to the current format when loaded.
+//
+// JSON lines storage has no header and each line is a JSON object with seed, moves and optional captures and checks,
+// e.g. {"seed":42,"moves":["e4","e5"],"captures":0,"checks":0}, and FEN of the starting position in "fen" for games not starting from the initial position
+// and generation options in "options" for games not generated with default options.
+// The format of existing files is detected by their first byte.
+//
+// All games in storage start from the same position and are generated with the same options.
+// Storage with games from another starting position or generated with other options than the generated games is refused,
+// because its games would be taken for generated ones.
+//
+// Compressed storage is a gzip compressed file with the same content. Because gzip files can't be appended to,
+// all games are kept in memory and the file is rewritten every compressedSaveInterval stored games and on Close.
+type Storage struct {
+	name string
+	f    *os.File
+
+	compressed bool
+	// Storage is in JSON lines format. For empty files it is set before loading, otherwise detected when loading.
+	JSONL bool
+	// Read only storage is never written to, not even migrated.
+	readOnly bool
+	// If set, malformed lines are logged and skipped when loading, instead of failing.
+	SkipBadLines bool
+	// Number of attempts to write and sync stored games, before giving up.
+	Attempts int
+	// FEN of the starting position of stored games, empty for the initial position. It is set before loading.
+	FEN string
+	// Options of the generation changing moves of stored games, e.g. "picker=captures", empty for default options. It is set before loading.
+	Options string
+	// Logs informational messages, like migration of the file. If nil, log.Printf is used.
+	Logf func(format string, v ...interface{})
+	// All games of compressed storage and the number of them not written to file yet.
+	games   []Game
+	unsaved int
+}
+
+// Open opens or creates storage file.
+// Storage is compressed if compress is true or the name has ".gz" extension.
+// Read only storage has to exist and is never written to.
+func Open(name string, compress, readOnly bool) (*Storage, error) {
+	compress = compress || strings.HasSuffix(name, ".gz")
+	flags := os.O_RDWR | os.O_CREATE | os.O_APPEND
+	if compress {
+		flags = os.O_RDONLY | os.O_CREATE
+	}
+	if readOnly {
+		flags = os.O_RDONLY
+	}
+	f, err := os.OpenFile(name, flags, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return &Storage{
+		name:       name,
+		f:          f,
+		compressed: compress,
+		readOnly:   readOnly,
+		Attempts:   1,
+	}, nil
+}
+
+// logf logs informational message with s.Logf, or log.Printf if it is not set.
+func (s *Storage) logf(format string, v ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// Game is a game read from a storage line.
+// Captures and checks are -1 for games read from storage in older format versions, which don't contain them.
+// FEN of the starting position is empty for games starting from the initial position, options are empty for games generated with default options.
+type Game struct {
+	Seed     int64
+	Moves    []string
+	Captures int
+	Checks   int
+	FEN      string
+	Options  string
+}
+
+func (sg Game) line() string {
+	if len(sg.Moves) == 0 {
+		return fmt.Sprint(sg.Seed, " ", 0, " ", sg.Captures, " ", sg.Checks)
+	}
+	return fmt.Sprint(sg.Seed, " ", len(sg.Moves), " ", sg.Captures, " ", sg.Checks, " ", strings.Join(sg.Moves, " "))
+}
+
+// jsonGame is a line of JSON lines storage.
+type jsonGame struct {
+	Seed     int64    `json:"seed"`
+	Moves    []string `json:"moves"`
+	Captures *int     `json:"captures,omitempty"`
+	Checks   *int     `json:"checks,omitempty"`
+	FEN      string   `json:"fen,omitempty"`
+	Options  string   `json:"options,omitempty"`
+}
+
+// jsonLine returns the game as a line of JSON lines storage.
+func (sg Game) jsonLine() string {
+	jsg := jsonGame{Seed: sg.Seed, Moves: sg.Moves, FEN: sg.FEN, Options: sg.Options}
+	if jsg.Moves == nil {
+		jsg.Moves = []string{}
+	}
+	if sg.Captures >= 0 && sg.Checks >= 0 {
+		jsg.Captures, jsg.Checks = &sg.Captures, &sg.Checks
+	}
+	b, _ := json.Marshal(jsg)
+	return string(b)
+}
+
+// encode returns the game as a storage line in JSON lines format if jsonl is true, or in the current text format otherwise.
+func (sg Game) encode(jsonl bool) string {
+	if jsonl {
+		return sg.jsonLine()
+	}
+	return sg.line()
+}
+
+// parseJSONLine returns the stored game from a line of JSON lines storage.
+func parseJSONLine(line string) (Game, error) {
+	jsg := jsonGame{}
+	if err := json.Unmarshal([]byte(line), &jsg); err != nil {
+		return Game{}, err
+	}
+	sg := Game{jsg.Seed, jsg.Moves, -1, -1, jsg.FEN, jsg.Options}
+	if jsg.Captures != nil && jsg.Checks != nil {
+		sg.Captures, sg.Checks = *jsg.Captures, *jsg.Checks
+	}
+	return sg, nil
+}
+
+// count sets the number of captures and checks of the game replayed from its moves, if they are not known.
+func (sg *Game) count() error {
+	if sg.Captures >= 0 && sg.Checks >= 0 {
+		return nil
+	}
+	g, err := gen.ReplaySANFrom(sg.FEN, sg.Moves)
+	if err != nil {
+		return err
+	}
+	sg.Captures, sg.Checks = gen.GameCounts(g)
+	return nil
+}
+
+// decoder reads games from lines of a storage file in any supported format.
+type decoder struct {
+	jsonl bool
+	// Format version from the header of text storage, 0 for storage without header.
+	version     int
+	headerFound bool
+	// FEN of the starting position of games in text storage, from the second header line.
+	fen      string
+	fenFound bool
+	// Generation options of games in text storage, from the options header line.
+	options      string
+	optionsFound bool
+	// Index of the last decoded game line and of the next one. Game lines are counted from 0, header lines are not counted.
+	index, next int
+}
+
+// newDecoder returns decoder of games from storage, which is read from br in JSON lines format if it starts with '{'.
+func newDecoder(br *bufio.Reader) *decoder {
+	d := &decoder{}
+	if b, err := br.Peek(1); err == nil {
+		d.jsonl = b[0] == '{'
+	}
+	return d
+}
+
+// decode returns the stored game from the line. For header lines header is true and the game is empty.
+// Unsupported header is reported as an error with header true, malformed game lines as an error with header false.
+func (d *decoder) decode(line string) (sg Game, header bool, err error) {
+	if !d.jsonl && d.next == 0 {
+		if !d.headerFound && strings.HasPrefix(line, HeaderPrefix) {
+			v, err := strconv.Atoi(strings.TrimPrefix(line, HeaderPrefix))
+			if err != nil || v < 2 || v > Version {
+				return Game{}, true, fmt.Errorf("unsupported header %q", line)
+			}
+			d.headerFound = true
+			d.version = v
+			return Game{}, true, nil
+		}
+		if d.version >= 4 && !d.fenFound && strings.HasPrefix(line, FENPrefix) {
+			d.fen = strings.TrimPrefix(line, FENPrefix)
+			d.fenFound = true
+			return Game{}, true, nil
+		}
+		if d.version >= 5 && !d.optionsFound && strings.HasPrefix(line, OptionsPrefix) {
+			d.options = strings.TrimPrefix(line, OptionsPrefix)
+			d.optionsFound = true
+			return Game{}, true, nil
+		}
+	}
+	d.index = d.next
+	d.next += 1
+	if d.jsonl {
+		sg, err := parseJSONLine(line)
+		if err != nil {
+			return Game{}, false, fmt.Errorf("line %d: %v", d.index, err)
+		}
+		return sg, false, nil
+	}
+	sg, n, err := parseLine(line, int64(d.index), d.version)
+	if err != nil {
+		return Game{}, false, fmt.Errorf("line %d: %v", d.index, err)
+	}
+	sg.FEN, sg.Options = d.fen, d.options
+	if n != len(sg.Moves) {
+		return Game{}, false, fmt.Errorf("line %d: number of moves %d does not correspond to number of SAN moves %d", d.index, n, len(sg.Moves))
+	}
+	return sg, false, nil
+}
+
+// Load reads all games from storage, calls fn for each of them and returns the set of stored seeds.
+// All stored games are read, even if there are more of them than games requested to generate, so they are all considered for selection.
+// If validate is true, every game is replayed from its starting position and has to reach the end of the game.
+// Storage with games starting from another position than s.FEN or generated with other options than s.Options is an error.
+// Loaded games have no positions, the number of half-moves is stored in capacity of Game.Positions slice.
+// Storage files in older formats are migrated to the current format.
+// Malformed lines are an error, unless SkipBadLines is set, then they are skipped and reported at the end.
+func (s *Storage) Load(validate bool, fn func(*game.Game)) (map[int64]bool, error) {
+	var r io.Reader = s.f
+	if s.compressed {
+		if fi, err := s.f.Stat(); err == nil && fi.Size() > 0 {
+			zr, err := gzip.NewReader(s.f)
+			if err != nil {
+				return nil, fmt.Errorf("decompressing: %v", err)
+			}
+			defer zr.Close()
+			r = zr
+		}
+	}
+	br := bufio.NewReader(r)
+	d := newDecoder(br)
+	if _, err := br.Peek(1); err == nil {
+		s.JSONL = d.jsonl
+	}
+	scanner := bufio.NewScanner(br)
+	seeds := map[int64]bool{}
+	games := []Game{}
+	skipped := []int{}
+	for scanner.Scan() {
+		sg, header, err := d.decode(scanner.Text())
+		if err == nil && !header && validate {
+			if verr := Validate(sg.FEN, sg.Moves); verr != nil {
+				err = fmt.Errorf("line %d: %v", d.index, verr)
+			}
+		}
+		if err != nil {
+			if header || !s.SkipBadLines {
+				return nil, err
+			}
+			log.Printf("Error in storage file \"%s\": %v", s.name, err)
+			skipped = append(skipped, d.index)
+			continue
+		}
+		if header {
+			continue
+		}
+		if sg.FEN != s.FEN {
+			return nil, fmt.Errorf("holds games starting from %s, not from %s, use another storage file for games starting from this position", StartDescription(sg.FEN), StartDescription(s.FEN))
+		}
+		if sg.Options != s.Options {
+			return nil, fmt.Errorf("holds games generated with %s, not with %s, use another storage file for games generated with these options", OptionsDescription(sg.Options), OptionsDescription(s.Options))
+		}
+		g := &game.Game{
+			Tags: map[string]string{
+				"#":        fmt.Sprint(sg.Seed),
+				"sanMoves": strings.Join(sg.Moves, " "),
+			},
+			Positions: make([]*position.Position, 0, len(sg.Moves)+1),
+		}
+		if sg.Captures >= 0 && sg.Checks >= 0 {
+			g.Tags[gen.TagCaptures] = fmt.Sprint(sg.Captures)
+			g.Tags[gen.TagChecks] = fmt.Sprint(sg.Checks)
+		}
+		if sg.FEN != "" {
+			g.Tags[gen.TagFEN] = sg.FEN
+		}
+		fn(g)
+		seeds[sg.Seed] = true
+		if (!s.JSONL && d.version != Version) || s.compressed {
+			games = append(games, sg)
+		}
+	}
+	if len(skipped) > 0 {
+		log.Printf("Skipped %d bad lines in storage file \"%s\", games for them are generated again: %v", len(skipped), s.name, skipped)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error reading storage file: %v", err)
+		return seeds, nil
+	}
+	if s.compressed {
+		s.games = games
+	}
+	// Header of text storage without games is rewritten, if it has FEN of another starting position or other options.
+	if !s.JSONL && (d.version != Version || d.fen != s.FEN || d.options != s.Options) && !s.readOnly {
+		if err := s.migrate(games); err != nil {
+			return nil, fmt.Errorf("migrating to new format: %v", err)
+		}
+	}
+	return seeds, nil
+}
+
+// parseLine returns the stored game and the number of half-moves written in the line.
+// Lines in storage with header (version 2 and later) have to contain the seed, lines of version 3 also the number of captures and checks.
+// Lines in storage without header (version is 0) may contain the seed (written by versions without header) and if not, index is used as the seed.
+func parseLine(line string, index int64, version int) (Game, int, error) {
+	parts := strings.Split(line, " ")
+	seeded := version >= 2
+	if len(parts) > 1 {
+		if n, err := strconv.Atoi(parts[1]); err == nil || seeded {
+			if err != nil {
+				return Game{}, 0, err
+			}
+			seed, err := strconv.ParseInt(parts[0], 10, 64)
+			if err != nil {
+				return Game{}, 0, err
+			}
+			if version < 3 {
+				return Game{seed, parts[2:], -1, -1, "", ""}, n, nil
+			}
+			if len(parts) < 4 {
+				return Game{}, 0, fmt.Errorf("expected seed, number of half-moves, captures, checks and moves, got %q", line)
+			}
+			captures, err := strconv.Atoi(parts[2])
+			if err != nil {
+				return Game{}, 0, err
+			}
+			checks, err := strconv.Atoi(parts[3])
+			if err != nil {
+				return Game{}, 0, err
+			}
+			return Game{seed, parts[4:], captures, checks, "", ""}, n, nil
+		}
+	}
+	if seeded {
+		return Game{}, 0, fmt.Errorf("expected seed, number of half-moves and moves, got %q", line)
+	}
+	n, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return Game{}, 0, err
+	}
+	return Game{index, parts[1:], -1, -1, "", ""}, n, nil
+}
+
+// migrate rewrites storage with header and games in the current format.
+// Games without the number of captures and checks are replayed to count them.
+func (s *Storage) migrate(games []Game) error {
+	if len(games) > 0 {
+		s.logf("Migrating %d games in storage file \"%s\" to new format", len(games), s.name)
+	}
+	for i := range games {
+		if err := games[i].count(); err != nil {
+			return fmt.Errorf("game with seed #%d: %v", games[i].Seed, err)
+		}
+	}
+	if s.compressed {
+		return s.save()
+	}
+	if err := WriteFile(s.name, s.FEN, s.Options, games, false, false); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.name, os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	s.f.Close()
+	s.f = f
+	return nil
+}
+
+// save rewrites compressed storage file with all games.
+func (s *Storage) save() error {
+	err := s.retry("saving compressed storage", func() error {
+		return WriteFile(s.name, s.FEN, s.Options, s.games, true, s.JSONL)
+	})
+	if err != nil {
+		return err
+	}
+	s.unsaved = 0
+	return nil
+}
+
+// ReadFile reads all games from the storage file in any supported format, including JSON lines, gzip compressed or not.
+// Unlike Storage.Load, the file is never migrated and any malformed line is an error. If a seed is stored more times, the last game is kept.
+func ReadFile(name string) (map[int64]Game, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	// Compressed files are recognized by gzip magic number.
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	zbr := bufio.NewReader(r)
+	d := newDecoder(zbr)
+	scanner := bufio.NewScanner(zbr)
+	games := map[int64]Game{}
+	for scanner.Scan() {
+		sg, header, err := d.decode(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		if !header {
+			games[sg.Seed] = sg
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+// WriteFile writes header and games to the storage file, gzip compressed if compress is true.
+// If startFEN is not empty, it is written to the second header line, games are expected to start from it.
+// If options are not empty, they are written to the next header line, games are expected to be generated with them.
+// In JSON lines format (jsonl is true) no header is written.
+// Games are written to a temporary file, which replaces storage file, so the old storage is kept intact on failure.
+func WriteFile(name, startFEN, options string, games []Game, compress, jsonl bool) error {
+	tmpName := name + ".tmp"
+	tmp, err := os.Create(tmpName)
+	if err != nil {
+		return err
+	}
+	var zw *gzip.Writer
+	var w *bufio.Writer
+	if compress {
+		zw = gzip.NewWriter(tmp)
+		w = bufio.NewWriter(zw)
+	} else {
+		w = bufio.NewWriter(tmp)
+	}
+	if !jsonl {
+		w.WriteString(Header + "\n")
+		if startFEN != "" {
+			w.WriteString(FENPrefix + startFEN + "\n")
+		}
+		if options != "" {
+			w.WriteString(OptionsPrefix + options + "\n")
+		}
+	}
+	for _, sg := range games {
+		w.WriteString(sg.encode(jsonl) + "\n")
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, name)
+}
+
+// Validate replays stored moves from the position given by startFEN and checks that the game ends with the last move.
+// Games stopped because of insufficient material or dead draw are considered ended.
+func Validate(startFEN string, moves []string) error {
+	g, err := gen.ReplaySANFrom(startFEN, moves)
+	if err != nil {
+		return err
+	}
+	last := g.Positions[len(g.Positions)-1]
+	if gs := g.Status(); gs == game.InProgress && !gen.IsInsufficientMaterial(last) && !gen.IsDeadDraw(last) {
+		return fmt.Errorf("game is still in progress after %d half-moves", len(moves))
+	}
+	return nil
+}
+
+// StartDescription describes the starting position of games given by FEN, which is empty for the initial position.
+func StartDescription(startFEN string) string {
+	if startFEN == "" {
+		return "the initial position"
+	}
+	return fmt.Sprintf("FEN %q", startFEN)
+}
+
+// OptionsDescription describes generation options of games, which are empty for default options.
+func OptionsDescription(options string) string {
+	if options == "" {
+		return "default options"
+	}
+	return fmt.Sprintf("options %q", options)
+}
+
+// Storable reports whether the game can be stored and loaded later as the same game.
+// Truncated games and games drawn for no progress depend on generation options, which are not recorded in storage,
+// and their stored moves end in a position still in progress, so they are not stored.
+func Storable(g *game.Game) bool {
+	return !gen.IsTruncated(g) && g.Tags[gen.TagAdjudication] != gen.DrawNoProgress
+}
+
+// Store appends the game to storage and syncs it to disk.
+// Compressed storage is written to disk only every compressedSaveInterval games.
+func (s *Storage) Store(g *game.Game) error {
+	if s.readOnly {
+		return nil
+	}
+	seed, _ := gen.GameSeed(g)
+	captures, checks := gen.GameCounts(g)
+	sg := Game{seed, gen.SANMoves(g), captures, checks, s.FEN, s.Options}
+	if s.compressed {
+		s.games = append(s.games, sg)
+		s.unsaved += 1
+		if s.unsaved < compressedSaveInterval {
+			return nil
+		}
+		if err := s.save(); err != nil {
+			log.Printf("Error saving compressed storage: %v", err)
+			return err
+		}
+		return nil
+	}
+	// Only the rest of a partially written line is written again.
+	data := []byte(sg.encode(s.JSONL) + "\n")
+	err := s.retry("storing game to storage", func() error {
+		n, err := s.f.Write(data)
+		data = data[n:]
+		return err
+	})
+	if err != nil {
+		log.Printf("Error storing game to storage: %v", err)
+		return err
+	}
+	if err := s.retry("syncing storage to disk", s.f.Sync); err != nil {
+		log.Printf("Error syncing storage to disk: %v", err)
+		return err
+	}
+	return nil
+}
+
+// First delay between attempts of failed storage operations, doubled after each attempt.
+const retryDelay = 100 * time.Millisecond
+
+// retry calls fn until it succeeds, at most s.Attempts times, with exponentially growing delay between attempts.
+// Failed attempts are logged with what describing the operation. The last error is returned if all attempts fail.
+func (s *Storage) retry(what string, fn func() error) error {
+	delay := retryDelay
+	for attempt := 1; ; attempt += 1 {
+		err := fn()
+		if err == nil || attempt >= s.Attempts {
+			return err
+		}
+		log.Printf("Error %s (attempt %d/%d), retrying in %v: %v", what, attempt, s.Attempts, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Close syncs storage to disk and closes it.
+func (s *Storage) Close() error {
+	if s.readOnly {
+		return s.f.Close()
+	}
+	if s.compressed {
+		if s.unsaved > 0 {
+			if err := s.save(); err != nil {
+				log.Printf("Error saving compressed storage: %v", err)
+			}
+		}
+		return s.f.Close()
+	}
+	if err := s.retry("syncing storage to disk", s.f.Sync); err != nil {
+		log.Printf("Error syncing storage to disk: %v", err)
+	}
+	return s.f.Close()
+}
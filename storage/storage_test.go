@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrewbackes/chess/game"
+	"github.com/jezek/chess-game-generator/gen"
+)
+
+// testGame returns stored game with the seed and n half-moves.
+// Moves are not legal, so storage with such games can't be validated.
+func testGame(seed int64, n int) Game {
+	return Game{seed, strings.Fields(strings.Repeat("Nf3 ", n)), 0, 0, "", ""}
+}
+
+// writeTestStorage writes text storage in the current format with the games.
+func writeTestStorage(t *testing.T, name string, games ...Game) {
+	t.Helper()
+	if err := WriteFile(name, "", "", games, false, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestFile writes the content to a new file in a temporary directory and returns its name.
+func writeTestFile(t *testing.T, content string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "storage.txt")
+	if err := os.WriteFile(name, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestStorageLoadConsidersAllStoredGames(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "storage.txt")
+	games := []Game{}
+	for i := 0; i < 500; i += 1 {
+		games = append(games, testGame(int64(i), i+1))
+	}
+	writeTestStorage(t, name, games...)
+	st, err := Open(name, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	c := gen.NewLengthCollector([]int{10, 100, 250, 450})
+	loaded := 0
+	stored, err := st.Load(false, func(g *game.Game) {
+		loaded += 1
+		c.Add(g)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != 500 || len(stored) != 500 {
+		t.Fatalf("loaded %d games with %d seeds, want 500", loaded, len(stored))
+	}
+	// With -searches 100, no game has to be generated and games with seeds over 99 are still selected.
+	for seed := int64(0); seed < 100; seed += 1 {
+		if !stored[seed] {
+			t.Errorf("seed #%d is not stored", seed)
+		}
+	}
+	for _, target := range c.Targets() {
+		g := c.Game(target)
+		if g == nil {
+			t.Errorf("no game for target %d", target)
+			continue
+		}
+		if got, want := g.Tags["#"], fmt.Sprint(target-1); got != want {
+			t.Errorf("game for target %d has seed #%s, want #%s", target, got, want)
+		}
+	}
+}
+
+func TestParseStorageLine(t *testing.T) {
+	for _, c := range []struct {
+		line    string
+		index   int64
+		version int
+		want    Game
+		n       int
+	}{
+		{"3 e4 e5 Nf3", 7, 0, Game{7, []string{"e4", "e5", "Nf3"}, -1, -1, "", ""}, 3},
+		{"0", 7, 0, Game{7, []string{}, -1, -1, "", ""}, 0},
+		{"42 3 e4 e5 Nf3", 7, 0, Game{42, []string{"e4", "e5", "Nf3"}, -1, -1, "", ""}, 3},
+		{"42 3 e4 e5 Nf3", 7, 2, Game{42, []string{"e4", "e5", "Nf3"}, -1, -1, "", ""}, 3},
+		{"42 3 0 0 e4 e5 Nf3", 7, 3, Game{42, []string{"e4", "e5", "Nf3"}, 0, 0, "", ""}, 3},
+		{"42 3 0 0 e4 e5 Nf3", 7, Version, Game{42, []string{"e4", "e5", "Nf3"}, 0, 0, "", ""}, 3},
+	} {
+		sg, n, err := parseLine(c.line, c.index, c.version)
+		if err != nil {
+			t.Errorf("parsing %q of version %d: %v", c.line, c.version, err)
+			continue
+		}
+		if n != c.n || sg.Seed != c.want.Seed || strings.Join(sg.Moves, " ") != strings.Join(c.want.Moves, " ") || sg.Captures != c.want.Captures || sg.Checks != c.want.Checks {
+			t.Errorf("parsing %q of version %d: got %+v with %d half-moves, want %+v with %d half-moves", c.line, c.version, sg, n, c.want, c.n)
+		}
+	}
+	for _, c := range []struct {
+		line    string
+		version int
+	}{
+		{"3 e4 e5 Nf3", 2},
+		{"42 3 e4 e5 Nf3", 3},
+		{"e4 e5", 0},
+	} {
+		if _, _, err := parseLine(c.line, 0, c.version); err == nil {
+			t.Errorf("parsing %q of version %d: expected error", c.line, c.version)
+		}
+	}
+}
+
+func TestStorageMigration(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		content string
+	}{
+		{"version 0", "3 e4 e5 Nf3\n2 d4 d5\n"},
+		{"version 2", HeaderPrefix + "2\n0 3 e4 e5 Nf3\n1 2 d4 d5\n"},
+		{"version 3", HeaderPrefix + "3\n0 3 0 0 e4 e5 Nf3\n1 2 0 0 d4 d5\n"},
+		{"version 4", HeaderPrefix + "4\n0 3 0 0 e4 e5 Nf3\n1 2 0 0 d4 d5\n"},
+	} {
+		name := writeTestFile(t, c.content)
+		st, err := Open(name, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st.Logf = t.Logf
+		loaded := map[string]string{}
+		stored, err := st.Load(false, func(g *game.Game) {
+			loaded[g.Tags["#"]] = g.Tags["sanMoves"]
+		})
+		st.Close()
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if len(stored) != 2 || loaded["0"] != "e4 e5 Nf3" || loaded["1"] != "d4 d5" {
+			t.Errorf("%s: loaded games %v", c.name, loaded)
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Header + "\n0 3 0 0 e4 e5 Nf3\n1 2 0 0 d4 d5\n"
+		if string(b) != want {
+			t.Errorf("%s: migrated storage is %q, want %q", c.name, b, want)
+		}
+	}
+}
+
+// readLines returns lines of the file.
+func readLines(t *testing.T, name string) []string {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func TestStorageLoadRefusesOtherStart(t *testing.T) {
+	const fen = "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"
+	for _, c := range []struct {
+		name    string
+		jsonl   bool
+		fen     string
+		options string
+	}{
+		{"text FEN", false, fen, ""},
+		{"text options", false, "", "picker=captures"},
+		{"JSON lines options", true, "", "picker=captures avoid-repetition"},
+	} {
+		name := filepath.Join(t.TempDir(), "storage.txt")
+		sg := testGame(0, 1)
+		sg.FEN, sg.Options = c.fen, c.options
+		if err := WriteFile(name, c.fen, c.options, []Game{sg}, false, c.jsonl); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []struct {
+			fen, options string
+			ok           bool
+		}{{c.fen, c.options, true}, {"", "", false}, {c.fen, c.options + " stop-dead", false}} {
+			st, err := Open(name, false, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			st.FEN, st.Options = want.fen, want.options
+			stored, err := st.Load(false, func(*game.Game) {})
+			st.Close()
+			if want.ok && (err != nil || !stored[0]) {
+				t.Errorf("%s: loading with FEN %q and options %q: seeds %v, %v", c.name, want.fen, want.options, stored, err)
+			} else if !want.ok && err == nil {
+				t.Errorf("%s: loading with FEN %q and options %q: expected error", c.name, want.fen, want.options)
+			}
+		}
+	}
+}